@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/winezer0/downtools/downfile"
+)
+
+// 子命令名称
+const (
+	CommandRun      = "run"
+	CommandList     = "list"
+	CommandStatus   = "status"
+	CommandValidate = "validate"
+	CommandClean    = "clean"
+	CommandFetch    = "fetch"
+)
+
+// CleanCommand clean 子命令参数，都未指定时清理下载缓存及未完成下载文件，帮助信息见 help.clean.<长参数名>
+type CleanCommand struct {
+	Cache   bool `long:"cache"`
+	Partial bool `long:"partial"`
+}
+
+// FetchCommand fetch 子命令参数，帮助信息见 help.fetch.<长参数名>
+type FetchCommand struct {
+	Output string `short:"o" long:"output"`
+	Args   struct {
+		URL string `positional-arg-name:"url"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// addCommands 注册所有子命令，未指定子命令时执行 run
+func addCommands(parser *flags.Parser, clean *CleanCommand, fetch *FetchCommand) {
+	parser.SubcommandsOptional = true
+	parser.AddCommand(CommandRun, downfile.Msg("help.command.run"), downfile.Msg("help.command.run.long"), &struct{}{})
+	parser.AddCommand(CommandList, downfile.Msg("help.command.list"), downfile.Msg("help.command.list.long"), &struct{}{})
+	parser.AddCommand(CommandStatus, downfile.Msg("help.command.status"), downfile.Msg("help.command.status.long"), &struct{}{})
+	parser.AddCommand(CommandValidate, downfile.Msg("help.command.validate"), downfile.Msg("help.command.validate.long"), &struct{}{})
+	parser.AddCommand(CommandClean, downfile.Msg("help.command.clean"), downfile.Msg("help.command.clean.long"), clean)
+	parser.AddCommand(CommandFetch, downfile.Msg("help.command.fetch"), downfile.Msg("help.command.fetch.long"), fetch)
+}
+
+// activeCommand 获取执行的子命令名称，未指定子命令时为 run
+func activeCommand(parser *flags.Parser) string {
+	if parser.Active == nil {
+		return CommandRun
+	}
+	return parser.Active.Name
+}
+
+// loadConfig 读取配置文件，配置文件中的全局设置在命令行未明确指定对应参数时生效
+func loadConfig(appConfig *AppConfig, parser *flags.Parser) (*downfile.ConfigFile, bool) {
+	configFile, err := downfile.LoadConfigFile(appConfig.ConfigFile)
+	if err != nil {
+		appLog.Errorf("main.load_config_failed", err)
+		return configFile, false
+	}
+	applySettings(appConfig, parser, configFile.Settings)
+	return configFile, true
+}
+
+// listItems 列出所有配置组及下载项
+func listItems(appConfig *AppConfig, parser *flags.Parser) int {
+	configFile, ok := loadConfig(appConfig, parser)
+	if !ok {
+		return ExitConfigError
+	}
+
+	group := ""
+	for _, task := range appConfig.selectTasks(configFile.Groups, true) {
+		if task.Group != group {
+			group = task.Group
+			items := configFile.Groups[group]
+			appLog.Infof("main.list_group", group, len(downfile.FilterEnableItems(items)), len(items))
+		}
+		item := task.Item
+		state := downfile.Msg("main.disabled")
+		if item.Enable {
+			state = downfile.Msg("main.enabled")
+		}
+		appLog.Infof("main.list_item", state, item.Module, item.FileName, len(item.DownloadURLs))
+		if item.GitHubRelease != "" {
+			appLog.Infof("main.list_release", item.GitHubRelease, item.Asset)
+		}
+		if len(item.Tags) > 0 {
+			appLog.Infof("main.list_tags", strings.Join(item.Tags, ", "))
+		}
+	}
+	return ExitOK
+}
+
+// showStatus 显示下载项的本地文件、缓存记录及下次运行是否会下载
+func showStatus(appConfig *AppConfig, parser *flags.Parser) int {
+	configFile, ok := loadConfig(appConfig, parser)
+	if !ok {
+		return ExitConfigError
+	}
+
+	// status 只读取状态，不迁移旧版缓存
+	cache := appConfig.openStateStore(false)
+	downloader, err := downfile.NewDownloader(
+		downfile.WithCache(cache),
+		downfile.WithOutputDir(appConfig.OutputDir),
+		downfile.WithForceUpdate(appConfig.ForceUpdate),
+	)
+	if err != nil {
+		appLog.Errorf("main.create_downloader_failed", err)
+		return ExitConfigError
+	}
+
+	group := ""
+	pending := 0
+	tasks := appConfig.selectTasks(configFile.Groups, appConfig.EnableAll)
+	for _, task := range tasks {
+		if task.Group != group {
+			group = task.Group
+			appLog.Infof("main.status_group", group)
+		}
+		status := downloader.ItemStatus(task.Item)
+		appLog.Infof("main.status_item", task.Item.Module, status.Path)
+		if status.Exists {
+			appLog.Infof("main.status_file", downfile.FormatSize(status.Size), status.ModTime.Format(time.DateTime))
+		} else {
+			appLog.Infof("main.status_file_missing")
+		}
+		switch {
+		case status.Cached && status.Tag != "":
+			appLog.Infof("main.status_cache_tag", downfile.FormatDuration(status.CacheAge), status.Tag)
+		case status.Cached:
+			appLog.Infof("main.status_cache", downfile.FormatDuration(status.CacheAge))
+		default:
+			appLog.Infof("main.status_cache_missing")
+		}
+		if status.Partial > 0 {
+			appLog.Infof("main.status_partial", downfile.FormatSize(status.Partial))
+		}
+		if status.NeedsUpdate {
+			pending++
+			appLog.Infof("main.status_update")
+		} else {
+			appLog.Infof("main.status_skip")
+		}
+	}
+	appLog.Infof("main.status_summary", pending, len(tasks))
+	return ExitOK
+}
+
+// cleanState 清理下载缓存及输出目录下的未完成下载文件
+func cleanState(appConfig *AppConfig, parser *flags.Parser, command *CleanCommand) int {
+	// 使用配置文件中设置的输出目录及缓存过期时间，配置文件不存在时使用命令行参数
+	if configFile, err := downfile.LoadConfigFile(appConfig.ConfigFile); err == nil {
+		applySettings(appConfig, parser, configFile.Settings)
+	}
+	both := !command.Cache && !command.Partial
+
+	exitCode := ExitOK
+	if command.Cache || both {
+		cache := appConfig.openStateStore(false)
+		if err := cache.Clear(); err != nil {
+			appLog.Errorf("main.clean_cache_failed", err)
+			exitCode = ExitPartialFail
+		} else {
+			appLog.Infof("main.clean_cache_done", cache.FilePath())
+		}
+	}
+	if command.Partial || both {
+		removed, err := downfile.RemovePartialDownloads(appConfig.OutputDir)
+		if err != nil {
+			appLog.Errorf("main.clean_partial_failed", err)
+			exitCode = ExitPartialFail
+		} else {
+			appLog.Infof("main.clean_partial_done", removed, appConfig.OutputDir)
+		}
+	}
+	return exitCode
+}
+
+// fetchURL 下载单个文件
+func fetchURL(appConfig *AppConfig, parser *flags.Parser, command *FetchCommand) int {
+	// 使用配置文件中设置的输出目录及代理，配置文件不存在时使用命令行参数
+	if configFile, err := downfile.LoadConfigFile(appConfig.ConfigFile); err == nil {
+		applySettings(appConfig, parser, configFile.Settings)
+	}
+
+	downloadURL := command.Args.URL
+	storePath := command.Output
+	if storePath == "" {
+		name := fetchFileName(downloadURL)
+		if name == "" {
+			appLog.Errorf("main.fetch_no_filename", downloadURL)
+			return ExitUsageError
+		}
+		storePath = filepath.Join(appConfig.OutputDir, name)
+	}
+
+	httpClient, err := downfile.CreateHTTPClient(&downfile.ClientConfig{
+		ConnectTimeout: appConfig.ConnectTimeout,
+		IdleTimeout:    appConfig.IdleTimeout,
+		ProxyURL:       appConfig.ProxyURL,
+	})
+	if err != nil {
+		appLog.Errorf("main.create_client_failed", err)
+		return ExitConfigError
+	}
+	// 下载记录写入输出目录的状态存储，与 run 共用
+	options := []downfile.Option{
+		downfile.WithHTTPClient(httpClient),
+		downfile.WithCache(appConfig.openStateStore(true)),
+		downfile.WithOutputDir(appConfig.OutputDir),
+		downfile.WithKeepOld(appConfig.KeepOld),
+		downfile.WithLogLevel(appLog.Level),
+	}
+	if appLog.Out != nil {
+		options = append(options, downfile.WithLogger(appLog.Out))
+	}
+	downloader, err := downfile.NewDownloader(options...)
+	if err != nil {
+		appLog.Errorf("main.create_downloader_failed", err)
+		return ExitConfigError
+	}
+
+	appLog.Infof("main.fetch_start", downloadURL, storePath)
+	if err := downloader.DownloadURL(context.Background(), downloadURL, storePath); err != nil {
+		appLog.Errorf("main.fetch_failed", err)
+		return ExitAllFailed
+	}
+	appLog.Infof("main.fetch_done", storePath)
+	return ExitOK
+}
+
+// fetchFileName 获取下载地址路径中的文件名，无法获取时返回空字符串
+func fetchFileName(downloadURL string) string {
+	parsed, err := url.Parse(downloadURL)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(parsed.Path)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
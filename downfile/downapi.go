@@ -0,0 +1,244 @@
+package downfile
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// Downloader 下载器，保存下载所需的全部设置，可在其他程序中嵌入使用
+type Downloader struct {
+	client         *http.Client
+	cache          CacheStore
+	cacheExpire    float64
+	outputDir      string
+	retries        int
+	concurrency    int
+	forceUpdate    bool
+	keepOld        bool
+	speedPolicy    SpeedPolicy
+	mirrorStrategy string
+	segments       int
+	rewriteRules   []RewriteRule
+	githubAPI      string
+	lock           *LockFile
+	logger         Logger
+	logLevel       slog.Level
+	board          *progressBoard
+	reporter       ProgressReporter
+	progressMode   string
+}
+
+// Option 下载器配置选项
+type Option func(*Downloader)
+
+// WithHTTPClient 设置下载使用的HTTP客户端
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Downloader) {
+		d.client = client
+	}
+}
+
+// WithCache 设置下载缓存存储，未设置时使用输出目录的默认状态存储
+func WithCache(cache CacheStore) Option {
+	return func(d *Downloader) {
+		d.cache = cache
+	}
+}
+
+// WithCacheExpire 设置默认状态存储的缓存过期时间（小时），默认为 DefaultCacheExpireHours
+// 使用 WithCache 设置的下载缓存时由其自身决定过期时间
+func WithCacheExpire(hours float64) Option {
+	return func(d *Downloader) {
+		d.cacheExpire = hours
+	}
+}
+
+// WithOutputDir 设置下载文件保存目录，下载项使用相对路径时基于此目录
+func WithOutputDir(dir string) Option {
+	return func(d *Downloader) {
+		d.outputDir = dir
+	}
+}
+
+// WithRetries 设置每个下载源的尝试次数
+func WithRetries(retries int) Option {
+	return func(d *Downloader) {
+		d.retries = retries
+	}
+}
+
+// WithConcurrency 设置并发下载数量
+func WithConcurrency(concurrency int) Option {
+	return func(d *Downloader) {
+		d.concurrency = concurrency
+	}
+}
+
+// WithForceUpdate 设置是否忽略缓存强制更新
+func WithForceUpdate(force bool) Option {
+	return func(d *Downloader) {
+		d.forceUpdate = force
+	}
+}
+
+// WithKeepOld 设置是否保留旧文件（重命名为.old）
+func WithKeepOld(keepOld bool) Option {
+	return func(d *Downloader) {
+		d.keepOld = keepOld
+	}
+}
+
+// WithSpeedPolicy 设置默认低速检测策略，下载项中配置的字段优先
+func WithSpeedPolicy(policy SpeedPolicy) Option {
+	return func(d *Downloader) {
+		d.speedPolicy = policy
+	}
+}
+
+// WithMirrorStrategy 设置默认下载源排序策略（ordered|fastest|healthiest|random），下载项中配置的策略优先
+func WithMirrorStrategy(strategy string) Option {
+	return func(d *Downloader) {
+		d.mirrorStrategy = strategy
+	}
+}
+
+// WithSegments 设置默认分段下载的并行连接数，下载项中配置的连接数优先，小于等于1时不分段
+func WithSegments(segments int) Option {
+	return func(d *Downloader) {
+		d.segments = segments
+	}
+}
+
+// WithRewriteRules 设置下载地址改写规则，规则生成的候选下载地址追加在下载项的下载地址之后
+// 下载器保存规则的副本并在 NewDownloader 中编译，规则无效时 NewDownloader 返回错误
+func WithRewriteRules(rules []RewriteRule) Option {
+	return func(d *Downloader) {
+		d.rewriteRules = append([]RewriteRule(nil), rules...)
+	}
+}
+
+// WithGitHubAPI 设置解析 github-release 下载项使用的 GitHub API 地址，默认为 DefaultGitHubAPI
+func WithGitHubAPI(apiURL string) Option {
+	return func(d *Downloader) {
+		d.githubAPI = apiURL
+	}
+}
+
+// WithLock 设置锁定文件，设置后只从锁定记录中的下载地址下载，并要求文件的SHA256与锁定记录一致
+func WithLock(lock *LockFile) Option {
+	return func(d *Downloader) {
+		d.lock = lock
+	}
+}
+
+// WithLogger 设置日志输出，默认输出到进度输出目标
+func WithLogger(logger Logger) Option {
+	return func(d *Downloader) {
+		d.logger = logger
+	}
+}
+
+// WithLogLevel 设置最低日志级别，日志输出实现 LevelLogger 时由其自行过滤
+func WithLogLevel(level slog.Level) Option {
+	return func(d *Downloader) {
+		d.logLevel = level
+	}
+}
+
+// WithProgressOutput 设置下载进度的输出目标，默认为标准输出，为nil时不输出进度
+func WithProgressOutput(w io.Writer) Option {
+	return func(d *Downloader) {
+		if w == nil {
+			w = io.Discard
+			d.progressMode = ProgressNone
+		}
+		d.board = newProgressBoard(w)
+	}
+}
+
+// WithProgressMode 设置进度输出模式（auto|tty|plain|json|none），默认 auto
+func WithProgressMode(mode string) Option {
+	return func(d *Downloader) {
+		d.progressMode = mode
+	}
+}
+
+// WithProgressReporter 设置自定义进度报告器，优先于进度输出模式
+func WithProgressReporter(reporter ProgressReporter) Option {
+	return func(d *Downloader) {
+		d.reporter = reporter
+	}
+}
+
+// NewDownloader 创建下载器，未设置的选项使用默认值，下载地址改写规则无效时返回错误
+func NewDownloader(opts ...Option) (*Downloader, error) {
+	d := &Downloader{
+		cacheExpire: DefaultCacheExpireHours,
+		outputDir:   ".",
+		retries:     1,
+		concurrency: 1,
+		speedPolicy: DefaultSpeedPolicy(),
+		board:       newProgressBoard(os.Stdout),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	// 改写规则在创建时编译，下载过程中只读，可被并发的下载任务共用
+	if err := CompileRewriteRules(d.rewriteRules); err != nil {
+		return nil, err
+	}
+	if d.client == nil {
+		d.client, _ = CreateHTTPClient(nil)
+	}
+	if d.cache == nil {
+		if d.cacheExpire <= 0 {
+			d.cacheExpire = DefaultCacheExpireHours
+		}
+		d.cache = NewStateStore(DefaultStateDir(d.outputDir), d.outputDir, d.cacheExpire)
+	}
+	if d.retries < 1 {
+		d.retries = 1
+	}
+	if d.githubAPI == "" {
+		d.githubAPI = DefaultGitHubAPI
+	}
+	if d.logger == nil {
+		d.logger = d.board
+	}
+	if d.reporter == nil {
+		reporter, err := newModeReporter(d.progressMode, d.board, d.log(""))
+		if err != nil {
+			reporter, _ = newModeReporter(ProgressAuto, d.board, d.log(""))
+		}
+		d.reporter = reporter
+	}
+	return d, nil
+}
+
+// log 创建带有前缀的下载项日志输出
+func (d *Downloader) log(prefix string) MsgLogger {
+	return MsgLogger{Prefix: prefix, Out: d.logger, Level: d.logLevel}
+}
+
+// DownloadItem 下载单个下载项，返回处理结果
+func (d *Downloader) DownloadItem(ctx context.Context, item DownItem) DownResult {
+	return d.processItem(ctx, item, newChecksumManifests(), d.log(""))
+}
+
+// DownloadURL 下载单个文件到 storePath，不使用重试及条件请求
+func (d *Downloader) DownloadURL(ctx context.Context, url, storePath string) error {
+	request := downloadRequest{
+		URL:         url,
+		StorePath:   storePath,
+		KeepOld:     d.keepOld,
+		SpeedPolicy: d.speedPolicy,
+		Logger:      d.log(""),
+		Cache:       d.cache,
+		Reporter:    d.reporter,
+	}
+	_, err := downloadFile(ctx, d.client, request)
+	return err
+}
@@ -2,17 +2,77 @@ package downfile
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 // DownloadCache 下载缓存结构
 type DownloadCache struct {
-	Files map[string]time.Time `json:"files"` // 文件路径 -> 最后下载时间
+	Files map[string]CacheEntry `json:"files"`           // 文件路径 -> 缓存记录
+	Hosts map[string]HostStats  `json:"hosts,omitempty"` // 下载源主机 -> 历史下载统计
 }
 
+// CacheEntry 文件缓存记录
+type CacheEntry struct {
+	DownloadTime time.Time `json:"download_time"`           // 最后下载（或确认未修改）时间
+	ETag         string    `json:"etag,omitempty"`          // 服务器返回的ETag
+	LastModified string    `json:"last_modified,omitempty"` // 服务器返回的Last-Modified
+	Size         int64     `json:"size,omitempty"`          // 文件大小
+	URL          string    `json:"url,omitempty"`           // 下载地址
+	Tag          string    `json:"tag,omitempty"`           // 下载的 GitHub Release 标签
+	SHA256       string    `json:"sha256,omitempty"`        // 下载文件的SHA256
+}
+
+// UnmarshalJSON 兼容旧版本缓存格式（仅记录最后下载时间）
+func (e *CacheEntry) UnmarshalJSON(data []byte) error {
+	var downloadTime time.Time
+	if err := json.Unmarshal(data, &downloadTime); err == nil {
+		*e = CacheEntry{DownloadTime: downloadTime}
+		return nil
+	}
+	type cacheEntryAlias CacheEntry
+	return json.Unmarshal(data, (*cacheEntryAlias)(e))
+}
+
+// HasValidator 判断缓存记录是否包含可用于条件请求的校验标识
+func (e CacheEntry) HasValidator() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// CacheStore 下载缓存存储接口，记录文件的下载信息及下载源统计，实现需要保证并发（包括多个进程）更新安全
+type CacheStore interface {
+	// GetEntry 获取文件缓存记录
+	GetEntry(filePath string) (CacheEntry, bool)
+	// UpdateEntry 更新文件缓存记录，下载时间设置为当前时间
+	UpdateEntry(filePath string, entry CacheEntry) error
+	// UpdateDownloadTime 更新文件下载时间，保留其他缓存信息
+	UpdateDownloadTime(filePath string) error
+	// NeedsUpdate 检查文件是否需要更新
+	NeedsUpdate(filePath string) bool
+	// CleanupExpired 清理过期缓存记录
+	CleanupExpired()
+	// RecordHost 记录下载源的一次下载结果
+	RecordHost(downloadUrl string, success bool, latency time.Duration, speed float64) error
+	// HostStats 获取所有下载源主机的历史下载统计
+	HostStats() map[string]HostStats
+	// Clear 删除所有缓存记录及下载源统计
+	Clear() error
+}
+
+// nopCache 不记录任何信息的下载缓存，文件总是需要更新
+type nopCache struct{}
+
+func (nopCache) GetEntry(string) (CacheEntry, bool)                    { return CacheEntry{}, false }
+func (nopCache) UpdateEntry(string, CacheEntry) error                  { return nil }
+func (nopCache) UpdateDownloadTime(string) error                       { return nil }
+func (nopCache) NeedsUpdate(string) bool                               { return true }
+func (nopCache) CleanupExpired()                                       {}
+func (nopCache) RecordHost(string, bool, time.Duration, float64) error { return nil }
+func (nopCache) HostStats() map[string]HostStats                       { return nil }
+func (nopCache) Clear() error                                          { return nil }
+
 // GetCacheFilePath 获取缓存文件路径
 func GetCacheFilePath() string {
 	// 缓存文件保存在用户主目录下
@@ -24,11 +84,96 @@ func GetCacheFilePath() string {
 	return filepath.Join(homeDir, CacheFileName)
 }
 
-// LoadDownloadCache 加载下载缓存
-func LoadDownloadCache() *DownloadCache {
-	cacheFilePath := GetCacheFilePath()
+// FileCache 基于JSON文件的下载缓存，读取-修改-写入过程使用文件锁保护，写入时先写临时文件再重命名
+type FileCache struct {
+	Path        string     // 缓存文件路径，为空时使用 GetCacheFilePath()
+	BaseDir     string     // 记录路径的基准目录，目录内的文件使用相对路径记录，为空时都使用绝对路径
+	ExpireHours float64    // 缓存过期时间（小时），小于等于0时使用 DefaultCacheExpireHours
+	Logger      Logger     // 警告输出，为nil时输出到终端
+	mu          sync.Mutex // 保护进程内的读取-修改-写入过程，进程间由文件锁保护
+}
+
+// NewFileCache 创建下载缓存，path 为空时使用默认缓存文件路径
+func NewFileCache(path string, expireHours float64) *FileCache {
+	return &FileCache{Path: path, ExpireHours: expireHours}
+}
+
+// defaultCache 旧版用户主目录下的下载缓存，只供已弃用的包级缓存函数使用
+var defaultCache = &FileCache{}
+
+// filePath 获取缓存文件路径
+func (c *FileCache) filePath() string {
+	if c.Path != "" {
+		return c.Path
+	}
+	return GetCacheFilePath()
+}
+
+// key 获取文件在缓存中的记录键：基准目录内的文件使用相对路径（/分隔），其他文件使用绝对路径
+func (c *FileCache) key(filePath string) (string, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", msgError("path.abs_failed", err)
+	}
+	if c.BaseDir == "" {
+		return absPath, nil
+	}
+	baseDir, err := filepath.Abs(c.BaseDir)
+	if err != nil {
+		return absPath, nil
+	}
+	if rel, err := filepath.Rel(baseDir, absPath); err == nil && pathWithin(baseDir, absPath) {
+		return filepath.ToSlash(rel), nil
+	}
+	return absPath, nil
+}
+
+// path 获取记录键对应的文件路径
+func (c *FileCache) path(key string) string {
+	if c.BaseDir == "" || filepath.IsAbs(key) {
+		return key
+	}
+	return filepath.Join(c.BaseDir, filepath.FromSlash(key))
+}
+
+// lock 获取进程内互斥锁及缓存文件的文件锁，返回释放函数
+// 无法获取文件锁（如目录不可写）时只使用进程内互斥锁并输出警告
+func (c *FileCache) lock() func() {
+	c.mu.Lock()
+	lockPath := c.filePath() + ".lock"
+	if err := MakeDirs(lockPath, true); err != nil {
+		c.log().Warnf("cache.lock_failed", err)
+		return c.mu.Unlock
+	}
+	unlock, err := lockFile(lockPath)
+	if err != nil {
+		c.log().Warnf("cache.lock_failed", err)
+		return c.mu.Unlock
+	}
+	return func() {
+		unlock()
+		c.mu.Unlock()
+	}
+}
+
+// expireHours 获取缓存过期时间（小时）
+func (c *FileCache) expireHours() float64 {
+	if c.ExpireHours > 0 {
+		return c.ExpireHours
+	}
+	return DefaultCacheExpireHours
+}
+
+// log 获取缓存警告的日志输出
+func (c *FileCache) log() MsgLogger {
+	return MsgLogger{Out: c.Logger}
+}
+
+// Load 加载下载缓存
+func (c *FileCache) Load() *DownloadCache {
+	cacheFilePath := c.filePath()
 	cache := &DownloadCache{
-		Files: make(map[string]time.Time),
+		Files: make(map[string]CacheEntry),
 	}
 
 	// 如果缓存文件不存在，返回空缓存
@@ -39,102 +184,247 @@ func LoadDownloadCache() *DownloadCache {
 	// 读取缓存文件
 	data, err := os.ReadFile(cacheFilePath)
 	if err != nil {
-		fmt.Printf("警告: 读取缓存文件失败: %v\n", err)
+		c.log().Warnf("cache.read_failed", err)
 		return cache
 	}
 
 	// 解析JSON
 	if err := json.Unmarshal(data, cache); err != nil {
-		fmt.Printf("警告: 解析缓存文件失败: %v\n", err)
+		c.log().Warnf("cache.parse_failed", err)
 		return &DownloadCache{
-			Files: make(map[string]time.Time),
+			Files: make(map[string]CacheEntry),
 		}
 	}
 
 	return cache
 }
 
-// SaveDownloadCache 保存下载缓存
-func SaveDownloadCache(cache *DownloadCache) error {
-	cacheFilePath := GetCacheFilePath()
+// Save 保存下载缓存
+func (c *FileCache) Save(cache *DownloadCache) error {
+	cacheFilePath := c.filePath()
 
 	// 将缓存转换为JSON
 	data, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
-		return fmt.Errorf("序列化缓存失败: %w", err)
+		return msgError("cache.marshal_failed", err)
 	}
 
-	// 写入文件
-	if err := os.WriteFile(cacheFilePath, data, 0644); err != nil {
-		return fmt.Errorf("写入缓存文件失败: %w", err)
+	// 先写入临时文件再重命名，其他进程读取时不会读到不完整的内容
+	if err := MakeDirs(cacheFilePath, true); err != nil {
+		return msgError("cache.write_failed", err)
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(cacheFilePath), filepath.Base(cacheFilePath)+".*.tmp")
+	if err != nil {
+		return msgError("cache.write_failed", err)
+	}
+	// 临时文件的权限为0600，保持原缓存文件的权限（不存在时为0644）
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(cacheFilePath); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	_, err = tempFile.Write(data)
+	if err == nil {
+		err = tempFile.Chmod(mode)
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), cacheFilePath)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return msgError("cache.write_failed", err)
 	}
-
 	return nil
 }
 
-// UpdateFileDownloadTime 更新文件下载时间
-func UpdateFileDownloadTime(filePath string) error {
+// UpdateDownloadTime 更新文件下载时间
+func (c *FileCache) UpdateDownloadTime(filePath string) error {
 	// 规范化文件路径
-	absPath, err := filepath.Abs(filePath)
+	key, err := c.key(filePath)
 	if err != nil {
-		return fmt.Errorf("获取绝对路径失败: %w", err)
+		return err
 	}
 
+	defer c.lock()()
+
 	// 加载缓存
-	cache := LoadDownloadCache()
+	cache := c.Load()
 
-	// 更新文件下载时间
-	cache.Files[absPath] = time.Now()
+	// 更新文件下载时间，保留其他缓存信息
+	entry := cache.Files[key]
+	entry.DownloadTime = time.Now()
+	cache.Files[key] = entry
 
 	// 保存缓存
-	return SaveDownloadCache(cache)
+	return c.Save(cache)
 }
 
-// CleanupExpiredCache 清理过期缓存记录
-func CleanupExpiredCache() {
-	cache := LoadDownloadCache()
+// UpdateEntry 更新文件缓存记录，下载时间设置为当前时间
+func (c *FileCache) UpdateEntry(filePath string, entry CacheEntry) error {
+	// 规范化文件路径
+	key, err := c.key(filePath)
+	if err != nil {
+		return err
+	}
+
+	defer c.lock()()
+
+	cache := c.Load()
+	entry.DownloadTime = time.Now()
+	cache.Files[key] = entry
+	return c.Save(cache)
+}
+
+// GetEntry 获取文件缓存记录
+func (c *FileCache) GetEntry(filePath string) (CacheEntry, bool) {
+	key, err := c.key(filePath)
+	if err != nil {
+		return CacheEntry{}, false
+	}
+
+	// 缓存文件通过重命名整体替换，读取时不需要文件锁
+	c.mu.Lock()
+	cache := c.Load()
+	c.mu.Unlock()
+
+	entry, exists := cache.Files[key]
+	return entry, exists
+}
+
+// CleanupExpired 清理过期缓存记录
+func (c *FileCache) CleanupExpired() {
+	defer c.lock()()
+
+	cache := c.Load()
 	now := time.Now()
 	changed := false
 
 	// 检查每个文件记录
-	for path, lastDownload := range cache.Files {
-		// 如果文件不存在或者时间超过7天，从缓存中删除
-		if !FileExists(path) || now.Sub(lastDownload).Hours() > CacheExpireHours { // 7天 = 168小时
-			delete(cache.Files, path)
+	for key, entry := range cache.Files {
+		// 如果文件不存在，或者超过缓存过期时间且没有可用于条件请求的校验标识，从缓存中删除
+		expired := now.Sub(entry.DownloadTime).Hours() > c.expireHours()
+		if !FileExists(c.path(key)) || (expired && !entry.HasValidator()) {
+			delete(cache.Files, key)
 			changed = true
 		}
 	}
 
 	// 如果有变化，保存缓存
 	if changed {
-		SaveDownloadCache(cache)
+		c.Save(cache)
 	}
 }
 
 // NeedsUpdate 检查文件是否需要更新
-func NeedsUpdate(filePath string) bool {
+// 缓存记录包含ETag/Last-Modified时总是返回true，由条件请求判断服务器文件是否变化
+func (c *FileCache) NeedsUpdate(filePath string) bool {
 	// 如果文件不存在，需要下载
 	if !FileExists(filePath) {
 		return true
 	}
 
-	// 规范化文件路径
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		// 如果无法获取绝对路径，保守起见返回需要更新
+	// 获取文件缓存记录
+	entry, exists := c.GetEntry(filePath)
+	if !exists {
+		// 如果没有记录，需要更新
 		return true
 	}
 
-	// 加载缓存
-	cache := LoadDownloadCache()
-
-	// 获取文件最后下载时间
-	lastDownload, exists := cache.Files[absPath]
-	if !exists {
-		// 如果没有记录，需要更新
+	// 可以使用条件请求低成本地确认文件是否变化
+	if entry.HasValidator() {
 		return true
 	}
 
 	// 检查是否超过缓存过期时间
-	return time.Since(lastDownload).Hours() > CacheExpireHours
+	return time.Since(entry.DownloadTime).Hours() > c.expireHours()
+}
+
+// Clear 删除缓存文件（包括下载源统计），缓存文件不存在时不做处理
+func (c *FileCache) Clear() error {
+	defer c.lock()()
+	if err := os.Remove(c.filePath()); err != nil && !os.IsNotExist(err) {
+		return msgError("cache.remove_failed", err)
+	}
+	return nil
+}
+
+// FilePath 获取缓存文件路径
+func (c *FileCache) FilePath() string {
+	return c.filePath()
+}
+
+// RecordHost 记录下载源的一次下载结果，用于下载源排序
+func (c *FileCache) RecordHost(downloadUrl string, success bool, latency time.Duration, speed float64) error {
+	defer c.lock()()
+
+	cache := c.Load()
+	if cache.Hosts == nil {
+		cache.Hosts = make(map[string]HostStats)
+	}
+	host := mirrorHost(downloadUrl)
+	stats := cache.Hosts[host]
+	stats.record(success, latency, speed)
+	cache.Hosts[host] = stats
+	return c.Save(cache)
+}
+
+// HostStats 获取所有下载源主机的历史下载统计
+func (c *FileCache) HostStats() map[string]HostStats {
+	c.mu.Lock()
+	cache := c.Load()
+	c.mu.Unlock()
+	return cache.Hosts
+}
+
+// LoadDownloadCache 加载下载缓存
+//
+// Deprecated: 包级缓存函数读写用户主目录下的旧版缓存文件（GetCacheFilePath），与 Downloader、ProcessDownTasks
+// 使用的输出目录状态存储（<输出目录>/.downtools/state.json）不是同一份记录，
+// 请使用 NewStateStore(DefaultStateDir(outputDir), outputDir, 0) 的对应方法。
+func LoadDownloadCache() *DownloadCache {
+	return defaultCache.Load()
+}
+
+// SaveDownloadCache 保存下载缓存
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func SaveDownloadCache(cache *DownloadCache) error {
+	return defaultCache.Save(cache)
+}
+
+// UpdateFileDownloadTime 更新文件下载时间
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func UpdateFileDownloadTime(filePath string) error {
+	return defaultCache.UpdateDownloadTime(filePath)
+}
+
+// UpdateFileCacheEntry 更新文件缓存记录，下载时间设置为当前时间
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func UpdateFileCacheEntry(filePath string, entry CacheEntry) error {
+	return defaultCache.UpdateEntry(filePath, entry)
+}
+
+// GetFileCacheEntry 获取文件缓存记录
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func GetFileCacheEntry(filePath string) (CacheEntry, bool) {
+	return defaultCache.GetEntry(filePath)
+}
+
+// CleanupExpiredCache 清理过期缓存记录
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func CleanupExpiredCache() {
+	defaultCache.CleanupExpired()
+}
+
+// NeedsUpdate 检查文件是否需要更新
+//
+// Deprecated: 读写用户主目录下的旧版缓存文件，见 LoadDownloadCache。
+func NeedsUpdate(filePath string) bool {
+	return defaultCache.NeedsUpdate(filePath)
 }
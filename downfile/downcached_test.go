@@ -0,0 +1,88 @@
+package downfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileCacheSaveKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	dir := t.TempDir()
+	cache := NewStateStore(dir, dir, 0)
+	storePath := filepath.Join(dir, "a.bin")
+
+	mode := func() os.FileMode {
+		t.Helper()
+		info, err := os.Stat(cache.FilePath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Mode().Perm()
+	}
+
+	if err := cache.UpdateEntry(storePath, CacheEntry{URL: "https://example.com/a.bin"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := mode(); got != 0644 {
+		t.Errorf("new state file mode = %o, want 644", got)
+	}
+
+	if err := os.Chmod(cache.FilePath(), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.UpdateDownloadTime(storePath); err != nil {
+		t.Fatal(err)
+	}
+	if got := mode(); got != 0640 {
+		t.Errorf("state file mode after save = %o, want 640", got)
+	}
+}
+
+func TestNewDownloaderDefaultCache(t *testing.T) {
+	outputDir := t.TempDir()
+	d, err := NewDownloader(WithOutputDir(outputDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, ok := d.cache.(*FileCache)
+	if !ok {
+		t.Fatalf("default cache = %T, want *FileCache", d.cache)
+	}
+	if want := filepath.Join(DefaultStateDir(outputDir), StateFileName); store.FilePath() != want {
+		t.Errorf("default cache path = %s, want %s", store.FilePath(), want)
+	}
+}
+
+func TestNewDownloaderCacheExpire(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want float64
+	}{
+		{name: "默认过期时间", want: DefaultCacheExpireHours},
+		{name: "设置过期时间", opts: []Option{WithCacheExpire(6)}, want: 6},
+		{name: "无效的过期时间", opts: []Option{WithCacheExpire(-1)}, want: DefaultCacheExpireHours},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDownloader(append([]Option{WithOutputDir(t.TempDir())}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.cache.(*FileCache).ExpireHours; got != tt.want {
+				t.Errorf("ExpireHours = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 每个下载器使用独立的终端输出协调器
+	a, _ := NewDownloader()
+	b, _ := NewDownloader()
+	if a.board == b.board {
+		t.Error("downloaders share the same progress board")
+	}
+}
@@ -0,0 +1,365 @@
+package downfile
+
+// builtinMessages 内置消息目录（语言 -> 消息键 -> 格式字符串）
+var builtinMessages = map[string]map[string]string{
+	LangZH: {
+		"cache.read_failed":                 "警告: 读取缓存文件失败: %v",
+		"cache.parse_failed":                "警告: 解析缓存文件失败: %v",
+		"cache.marshal_failed":              "序列化缓存失败: %w",
+		"cache.write_failed":                "写入缓存文件失败: %w",
+		"path.abs_failed":                   "获取绝对路径失败: %w",
+		"cache.update_failed":               "    错误: 更新下载缓存失败: %v",
+		"checksum.unsupported_algo":         "不支持的校验算法: %s",
+		"checksum.mismatch":                 "文件校验失败: %s 期望 %s, 实际 %s",
+		"checksum.read_manifest_failed":     "读取校验清单失败: %w",
+		"checksum.fetch_manifest_failed":    "获取校验清单失败 %s: %v",
+		"checksum.not_in_manifest":          "校验清单 %s 中未找到 %s 的校验值",
+		"client.proxy_parse_failed":         "解析代理URL失败: %w",
+		"extract.unknown_type":              "无法识别压缩包类型: %s",
+		"extract.absolute_member":           "压缩包成员使用了绝对路径: %s",
+		"extract.member_escape":             "压缩包成员路径越界: %s",
+		"extract.duplicate_output":          "压缩包中存在重复的输出文件: %s",
+		"extract.member_failed":             "解压 %s 失败: %w",
+		"extract.read_tar_failed":           "读取tar失败: %w",
+		"extract.read_zip_failed":           "读取zip失败: %w",
+		"extract.read_zip_member_failed":    "读取zip成员 %s 失败: %w",
+		"extract.mkdir_failed":              "创建解压目录失败: %w",
+		"extract.mktemp_failed":             "创建解压临时目录失败: %w",
+		"extract.unsupported_type":          "不支持的压缩包类型: %s",
+		"extract.no_match":                  "压缩包中没有匹配的文件",
+		"extract.read_archive_failed":       "读取压缩包失败: %w",
+		"extract.failed":                    "解压 %s 失败: %v",
+		"extract.extracted":                 "    已解压: %s",
+		"extract.save_marker_failed":        "    警告: 保存解压记录失败: %v",
+		"item.run_timeout_skip":             "  已超过总运行时间限制，跳过 %s",
+		"item.run_timeout":                  "已超过总运行时间限制",
+		"item.skip_existing":                "  文件 %s 已存在且不需要更新，跳过下载",
+		"item.error":                        "  错误: %v",
+		"item.mkdir_failed":                 "  目录[%s]初始化失败:%v",
+		"item.start":                        "  开始下载 %s...",
+		"item.checksum_unavailable":         "  错误: %v，无法校验 %s",
+		"item.github_convert":               "    转换GitHub URL: %s -> %s",
+		"item.retry":                        "    第 %d 次重试下载...",
+		"item.try_url":                      "    尝试从 %s 下载...",
+		"item.not_modified":                 "    文件 %s 未修改，无需更新",
+		"item.download_failed":              "    下载失败: %v",
+		"item.run_timeout_stop":             "    已超过总运行时间限制，停止下载",
+		"item.timeout_next":                 "    下载超时，尝试下一个下载源",
+		"item.not_found":                    "    资源不存在 (404)，请检查配置中的URL是否正确",
+		"item.checksum_next":                "    下载源内容校验失败，尝试下一个下载源",
+		"item.retry_wait":                   "    等待 %v 后重试...",
+		"item.success":                      "    成功下载 %s 到 %s",
+		"item.resource_missing":             "  警告: %s 的资源不存在，请检查配置文件中的URL",
+		"item.all_failed":                   "  错误: 所有下载源都失败，无法下载 %s",
+		"group.start":                       "\n处理配置组: %s",
+		"download.mkdir_failed":             "创建目录失败: %w",
+		"download.range_invalid_restart":    "    续传范围无效，重新开始下载",
+		"download.resume_from":              "    从 %s 处继续下载",
+		"download.resume_unsupported":       "    服务器资源已变化或不支持续传，重新开始下载",
+		"download.save_partial_meta_failed": "    警告: 保存续传信息失败: %v",
+		"download.low_speed_cancelled":      "下载已取消: 速度过低，低于最小要求 (%s/s)，网络可能存在问题",
+		"download.copy_failed":              "下载内容失败: %w",
+		"download.close_failed":             "关闭文件失败: %w",
+		"download.checksum_ok":              "    文件校验通过",
+		"download.timeout":                  "下载超时: 超过允许的最长下载时间",
+		"replace.remove_backup_failed":      "删除旧的备份文件失败: %w",
+		"replace.backup_failed":             "备份旧文件失败: %w",
+		"replace.backed_up":                 "    已备份旧文件为: %s",
+		"replace.remove_old_failed":         "错误:删除旧文件失败: %w",
+		"replace.rename_failed":             "错误: 重命名临时文件失败: %w",
+		"http.request_failed":               "HTTP请求失败: %w",
+		"http.not_modified":                 "文件未修改 (304 Not Modified)",
+		"http.not_found":                    "资源不存在，HTTP状态码: %d (404 Not Found)",
+		"http.range_not_satisfiable":        "续传范围无效，HTTP状态码: %d",
+		"http.status_failed":                "HTTP请求失败，状态码: %d",
+		"partial.invalid_content_range":     "无效的Content-Range: %s",
+		"partial.offset_mismatch":           "服务器返回的续传起始位置 %d 与本地已下载大小 %d 不一致",
+		"partial.open_failed":               "打开未完成下载文件失败: %w",
+		"partial.read_failed":               "读取未完成下载文件失败: %w",
+		"partial.seek_failed":               "定位未完成下载文件失败: %w",
+		"partial.truncate_failed":           "截断未完成下载文件失败: %w",
+		"partial.create_failed":             "创建临时文件失败: %w",
+		"progress.start_size":               "    [%s] 开始传输，文件大小 %s",
+		"progress.start_unknown":            "    [%s] 开始传输，文件大小未知",
+		"progress.item_line":                "    [%s] %s",
+		"progress.unsupported_mode":         "不支持的进度输出模式: %s",
+		"progress.known_eta":                "下载进度: %.1f%% (%s/%s) 速度: %s 剩余时间: %s",
+		"progress.known_no_eta":             "下载进度: %.1f%% (%s/%s) 速度: %s 剩余时间: 未知",
+		"progress.known_waiting":            "下载进度: %.1f%% (%s/%s) 等待数据传输...",
+		"progress.unknown_speed":            "已下载: %s 速度: %s",
+		"progress.unknown_waiting":          "已下载: %s 等待数据传输...",
+		"report.unsupported_format":         "不支持的报告格式: %s",
+		"report.build_failed":               "生成报告失败: %w",
+		"report.mkdir_failed":               "创建报告目录失败: %w",
+		"report.write_failed":               "写入报告文件失败: %w",
+		"tracker.low_speed":                 "下载速度过低 (%s/s)",
+		"tracker.low_speed_cancelled":       "    下载已取消: 速度过低 (%s/s)，低于最小要求 (%s/s)，网络可能存在问题",
+		"tracker.summary":                   "    下载完成: 总大小 %s, 用时 %s, 平均速度 %s/s",
+		"config.read_failed":                "读取配置文件失败: %w",
+		"config.parse_failed":               "解析YAML失败: %w",
+		"cleanup.find_failed":               "查找未完成下载文件失败: %w",
+		"cleanup.remove_failed":             "警告: 删除未完成下载文件失败 %s: %v",
+		"duration.hms":                      "%d小时%d分%d秒",
+		"duration.ms":                       "%d分%d秒",
+		"duration.s":                        "%d秒",
+		"mirror.record_failed":              "    警告: 记录下载源统计失败: %v",
+		"mirror.unknown_strategy":           "    警告: 不支持的下载源排序策略 %s，按配置顺序下载",
+		"mirror.order":                      "    下载源顺序 (%s): %s",
+		"probe.item":                        "  探测 %s 的 %d 个下载源...",
+		"probe.ok":                          "    可用: %s (HTTP %d, 大小 %s, 延迟 %v)",
+		"probe.failed":                      "    不可用: %s (%v)",
+		"probe.size_unknown":                "未知",
+		"probe.none_reachable":              "  错误: %s 没有可用的下载源",
+		"probe.size_mismatch":               "  警告: %s 的下载源返回的文件大小不一致",
+		"race.winner":                       "    竞速胜出: %s (用时 %v)",
+		"race.loser_not_found":              "    竞速: %s 资源不存在 (404)",
+		"race.loser_failed":                 "    竞速: %s 不可用: %v",
+		"race.no_winner":                    "    竞速: 所有下载源都未成功响应，按原有顺序尝试下载",
+		"segment.truncate_failed":           "预分配未完成下载文件失败: %w",
+		"segment.start":                     "    使用 %d 个连接分段下载",
+		"segment.start_mirrors":             "    使用 %d 个连接从 %d 个下载源分段下载",
+		"segment.mirror_failed":             "    警告: 从 %s 下载分段失败，改用主下载源: %v",
+		"segment.mirror_skipped":            "    下载源 %s 不可用或文件大小不一致，不用于分段下载",
+		"segment.range_ignored":             "下载源 %s 未按范围返回分段",
+		"segment.incomplete":                "分段 %d-%d 数据不完整: 收到 %d 字节, 期望 %d 字节",
+		"release.invalid_repo":              "无效的 GitHub 仓库: %s，应为 owner/repo 格式",
+		"release.asset_required":            "GitHub Release %s 未配置 asset 文件名匹配模式",
+		"release.asset_not_found":           "GitHub Release %s 中没有匹配 %s 的文件",
+		"release.request_failed":            "请求 GitHub API 失败: %v",
+		"release.not_found":                 "GitHub Release 不存在: %s",
+		"release.rate_limited":              "GitHub API 请求次数超过限制，请设置 %s 环境变量",
+		"release.status_failed":             "GitHub API 请求失败，状态码: %d",
+		"release.parse_failed":              "解析 GitHub API 响应失败: %v",
+		"release.fallback":                  "    警告: %v，使用配置的下载地址",
+		"release.resolved":                  "    GitHub Release %s: %s (%s)",
+		"release.unchanged":                 "  文件 %s 已是最新的 Release (%s)，无需更新",
+		"rewrite.match_required":            "改写规则缺少 match 正则表达式",
+		"rewrite.target_required":           "改写规则 %s 未配置 urls 或 prefixes",
+		"rewrite.invalid_match":             "改写规则的正则表达式 %s 无效: %v",
+		"config.invalid_rewrite_rule":       "无效的下载地址改写规则: %w",
+		"config.unsupported_version":        "不支持的配置文件版本 %d，当前最高支持版本 %d",
+		"config.group_invalid":              "配置组 %s 无效: %w",
+		"config.group_type":                 "第 %d 行: 配置组应为下载项列表或包含 defaults/items 的映射",
+		"config.defaults_invalid":           "defaults 无效: %w",
+		"validate.syntax":                   "YAML语法错误: %v",
+		"validate.empty":                    "配置文件为空",
+		"validate.root_type":                "配置文件顶层应为映射",
+		"validate.parse":                    "%v",
+		"validate.groups_type":              "groups 应为配置组名称到下载项的映射",
+		"validate.unknown_field":            "未知字段 %s",
+		"validate.items_type":               "配置组 %s 应为下载项列表",
+		"validate.type":                     "字段类型错误: %s",
+		"validate.duplicate_module":         "模块名 %s 与第 %d 行的下载项重复",
+		"validate.duplicate_module_group":   "模块名 %s 与配置组 %s 第 %d 行的下载项相同",
+		"validate.duplicate_filename":       "下载文件 %s 与配置组 %s 第 %d 行的下载项相同",
+		"validate.module_required":          "配置组 %s 的下载项缺少 module",
+		"validate.filename_required":        "下载项 %s 缺少 filename",
+		"validate.filename_absolute":        "文件路径 %s 是输出目录之外的绝对路径",
+		"validate.filename_escape":          "文件路径 %s 超出了输出目录",
+		"validate.extract_escape":           "解压目录 %s 超出了输出目录",
+		"validate.urls_required":            "下载项 %s 没有配置下载地址",
+		"validate.invalid_url":              "无效的下载地址: %s",
+		"validate.invalid_strategy":         "不支持的下载源排序策略: %s",
+		"cleanup.remove_partial_failed":     "删除未完成下载文件 %s 失败: %w",
+		"cache.remove_failed":               "删除缓存文件失败: %w",
+		"filter.invalid_pattern":            "无效的通配符模式: %s",
+		"lock.read_failed":                  "读取锁定文件失败: %w",
+		"lock.parse_failed":                 "解析锁定文件 %s 失败: %w",
+		"lock.unsupported_version":          "不支持的锁定文件版本 %d（最高支持 %d）",
+		"lock.write_failed":                 "写入锁定文件失败: %w",
+		"lock.stat_failed":                  "获取文件 %s 信息失败: %w",
+		"lock.item_missing":                 "锁定文件中没有 %s (%s) 的记录",
+		"lock.item_incomplete":              "锁定文件中 %s 的记录缺少下载地址或SHA256",
+		"cache.lock_failed":                 "获取缓存文件锁失败，只保证进程内的互斥: %v",
+		"partial.lock_failed":               "    获取未完成下载文件锁失败，不加锁继续下载: %v",
+		"partial.locked_wait":               "    其他进程正在下载 %s，等待其完成",
+		"item.timeout_stop":                 "    超过下载项时间限制 (%v)，停止下载",
+		"extract.too_large":                 "解压出的文件超过大小上限 %s",
+		"extract.keep_old_failed":           "    警告: 保留旧文件 %s 失败: %v",
+		"item.no_urls":                      "下载项没有配置下载地址",
+		"lock.no_url_skip":                  "  警告: 无法确定 %s 的下载地址，不写入锁定记录",
+		"release.response_too_large":        "GitHub API 响应超过大小限制（%d 字节）: %s",
+		"extract.overwrites_archive":        "解压出的文件 %s 会覆盖压缩包本身",
+		"item.extract_next":                 "    下载的压缩包解压失败，尝试下一个下载源",
+	},
+	LangEN: {
+		"cache.read_failed":                 "Warning: failed to read cache file: %v",
+		"cache.parse_failed":                "Warning: failed to parse cache file: %v",
+		"cache.marshal_failed":              "failed to serialize cache: %w",
+		"cache.write_failed":                "failed to write cache file: %w",
+		"path.abs_failed":                   "failed to get absolute path: %w",
+		"cache.update_failed":               "    Error: failed to update download cache: %v",
+		"checksum.unsupported_algo":         "unsupported checksum algorithm: %s",
+		"checksum.mismatch":                 "checksum mismatch: %s expected %s, got %s",
+		"checksum.read_manifest_failed":     "failed to read checksum manifest: %w",
+		"checksum.fetch_manifest_failed":    "failed to fetch checksum manifest %s: %v",
+		"checksum.not_in_manifest":          "no checksum for %[2]s found in manifest %[1]s",
+		"client.proxy_parse_failed":         "failed to parse proxy URL: %w",
+		"extract.unknown_type":              "cannot detect archive type: %s",
+		"extract.absolute_member":           "archive member uses an absolute path: %s",
+		"extract.member_escape":             "archive member path escapes destination: %s",
+		"extract.duplicate_output":          "duplicate output file in archive: %s",
+		"extract.member_failed":             "failed to extract %s: %w",
+		"extract.read_tar_failed":           "failed to read tar: %w",
+		"extract.read_zip_failed":           "failed to read zip: %w",
+		"extract.read_zip_member_failed":    "failed to read zip member %s: %w",
+		"extract.mkdir_failed":              "failed to create extract directory: %w",
+		"extract.mktemp_failed":             "failed to create temporary extract directory: %w",
+		"extract.unsupported_type":          "unsupported archive type: %s",
+		"extract.no_match":                  "no matching files in archive",
+		"extract.read_archive_failed":       "failed to read archive: %w",
+		"extract.failed":                    "failed to extract %s: %v",
+		"extract.extracted":                 "    Extracted: %s",
+		"extract.save_marker_failed":        "    Warning: failed to save extract record: %v",
+		"item.run_timeout_skip":             "  Total run time limit exceeded, skipping %s",
+		"item.run_timeout":                  "total run time limit exceeded",
+		"item.skip_existing":                "  File %s already exists and needs no update, skipping download",
+		"item.error":                        "  Error: %v",
+		"item.mkdir_failed":                 "  Failed to initialize directory [%s]: %v",
+		"item.start":                        "  Downloading %s...",
+		"item.checksum_unavailable":         "  Error: %v, cannot verify %s",
+		"item.github_convert":               "    Converted GitHub URL: %s -> %s",
+		"item.retry":                        "    Retry attempt %d...",
+		"item.try_url":                      "    Trying %s...",
+		"item.not_modified":                 "    File %s not modified, no update needed",
+		"item.download_failed":              "    Download failed: %v",
+		"item.run_timeout_stop":             "    Total run time limit exceeded, stopping download",
+		"item.timeout_next":                 "    Download timed out, trying next source",
+		"item.not_found":                    "    Resource not found (404), please check the URL in the config",
+		"item.checksum_next":                "    Content verification failed, trying next source",
+		"item.retry_wait":                   "    Retrying in %v...",
+		"item.success":                      "    Downloaded %s to %s",
+		"item.resource_missing":             "  Warning: resource for %s not found, please check the URLs in the config file",
+		"item.all_failed":                   "  Error: all sources failed, cannot download %s",
+		"group.start":                       "\nProcessing group: %s",
+		"download.mkdir_failed":             "failed to create directory: %w",
+		"download.range_invalid_restart":    "    Invalid resume range, restarting download",
+		"download.resume_from":              "    Resuming from %s",
+		"download.resume_unsupported":       "    Resource changed or resume not supported, restarting download",
+		"download.save_partial_meta_failed": "    Warning: failed to save resume info: %v",
+		"download.low_speed_cancelled":      "download cancelled: speed below the required minimum (%s/s), the network may have problems",
+		"download.copy_failed":              "failed to download content: %w",
+		"download.close_failed":             "failed to close file: %w",
+		"download.checksum_ok":              "    Checksum verified",
+		"download.timeout":                  "download timed out: exceeded the maximum allowed download time",
+		"replace.remove_backup_failed":      "failed to remove old backup file: %w",
+		"replace.backup_failed":             "failed to back up old file: %w",
+		"replace.backed_up":                 "    Backed up old file as: %s",
+		"replace.remove_old_failed":         "failed to remove old file: %w",
+		"replace.rename_failed":             "failed to rename temporary file: %w",
+		"http.request_failed":               "HTTP request failed: %w",
+		"http.not_modified":                 "file not modified (304 Not Modified)",
+		"http.not_found":                    "resource not found, HTTP status: %d (404 Not Found)",
+		"http.range_not_satisfiable":        "invalid resume range, HTTP status: %d",
+		"http.status_failed":                "HTTP request failed, status: %d",
+		"partial.invalid_content_range":     "invalid Content-Range: %s",
+		"partial.offset_mismatch":           "resume offset %d returned by server does not match local size %d",
+		"partial.open_failed":               "failed to open partial download file: %w",
+		"partial.read_failed":               "failed to read partial download file: %w",
+		"partial.seek_failed":               "failed to seek partial download file: %w",
+		"partial.truncate_failed":           "failed to truncate partial download file: %w",
+		"partial.create_failed":             "failed to create temporary file: %w",
+		"progress.start_size":               "    [%s] Transfer started, size %s",
+		"progress.start_unknown":            "    [%s] Transfer started, size unknown",
+		"progress.item_line":                "    [%s] %s",
+		"progress.unsupported_mode":         "unsupported progress mode: %s",
+		"progress.known_eta":                "Progress: %.1f%% (%s/%s) speed: %s remaining: %s",
+		"progress.known_no_eta":             "Progress: %.1f%% (%s/%s) speed: %s remaining: unknown",
+		"progress.known_waiting":            "Progress: %.1f%% (%s/%s) waiting for data...",
+		"progress.unknown_speed":            "Downloaded: %s speed: %s",
+		"progress.unknown_waiting":          "Downloaded: %s waiting for data...",
+		"report.unsupported_format":         "unsupported report format: %s",
+		"report.build_failed":               "failed to build report: %w",
+		"report.mkdir_failed":               "failed to create report directory: %w",
+		"report.write_failed":               "failed to write report file: %w",
+		"tracker.low_speed":                 "download speed too low (%s/s)",
+		"tracker.low_speed_cancelled":       "    Download cancelled: speed (%s/s) below the required minimum (%s/s), the network may have problems",
+		"tracker.summary":                   "    Download finished: size %s, time %s, average speed %s/s",
+		"config.read_failed":                "failed to read config file: %w",
+		"config.parse_failed":               "failed to parse YAML: %w",
+		"cleanup.find_failed":               "failed to find partial download files: %w",
+		"cleanup.remove_failed":             "Warning: failed to remove partial download file %s: %v",
+		"duration.hms":                      "%dh%dm%ds",
+		"duration.ms":                       "%dm%ds",
+		"duration.s":                        "%ds",
+		"mirror.record_failed":              "    Warning: failed to record mirror statistics: %v",
+		"mirror.unknown_strategy":           "    Warning: unsupported mirror strategy %s, using config order",
+		"mirror.order":                      "    Mirror order (%s): %s",
+		"probe.item":                        "  Probing %s (%d mirrors)...",
+		"probe.ok":                          "    Reachable: %s (HTTP %d, size %s, latency %v)",
+		"probe.failed":                      "    Unreachable: %s (%v)",
+		"probe.size_unknown":                "unknown",
+		"probe.none_reachable":              "  Error: no reachable mirror for %s",
+		"probe.size_mismatch":               "  Warning: mirrors of %s report different file sizes",
+		"race.winner":                       "    Race winner: %s (%v)",
+		"race.loser_not_found":              "    Race: %s not found (404)",
+		"race.loser_failed":                 "    Race: %s unreachable: %v",
+		"race.no_winner":                    "    Race: no mirror responded successfully, trying mirrors in order",
+		"segment.truncate_failed":           "failed to preallocate partial file: %w",
+		"segment.start":                     "    Downloading in %d segments",
+		"segment.start_mirrors":             "    Downloading in %d segments from %d mirrors",
+		"segment.mirror_failed":             "    Warning: segment from %s failed, retrying from primary source: %v",
+		"segment.mirror_skipped":            "    Mirror %s is unreachable or reports a different size, not used for segments",
+		"segment.range_ignored":             "mirror %s did not honour the range request",
+		"segment.incomplete":                "segment %d-%d incomplete: got %d bytes, expected %d",
+		"release.invalid_repo":              "invalid GitHub repository: %s, expected owner/repo",
+		"release.asset_required":            "no asset pattern configured for GitHub release %s",
+		"release.asset_not_found":           "no asset matching %[2]s found in GitHub releases of %[1]s",
+		"release.request_failed":            "GitHub API request failed: %v",
+		"release.not_found":                 "GitHub release not found: %s",
+		"release.rate_limited":              "GitHub API rate limit exceeded, please set the %s environment variable",
+		"release.status_failed":             "GitHub API request failed, status: %d",
+		"release.parse_failed":              "failed to parse GitHub API response: %v",
+		"release.fallback":                  "    Warning: %v, using configured download URLs",
+		"release.resolved":                  "    GitHub release %s: %s (%s)",
+		"release.unchanged":                 "  File %s is already the latest release (%s), no update needed",
+		"rewrite.match_required":            "rewrite rule has no match expression",
+		"rewrite.target_required":           "rewrite rule %s has neither urls nor prefixes",
+		"rewrite.invalid_match":             "invalid rewrite rule expression %s: %v",
+		"config.invalid_rewrite_rule":       "invalid rewrite rule: %w",
+		"config.unsupported_version":        "unsupported config version %d, the highest supported version is %d",
+		"config.group_invalid":              "invalid group %s: %w",
+		"config.group_type":                 "line %d: a group must be a list of items or a mapping with defaults/items",
+		"config.defaults_invalid":           "invalid defaults: %w",
+		"validate.syntax":                   "YAML syntax error: %v",
+		"validate.empty":                    "config file is empty",
+		"validate.root_type":                "the top level of the config must be a mapping",
+		"validate.parse":                    "%v",
+		"validate.groups_type":              "groups must be a mapping of group names to items",
+		"validate.unknown_field":            "unknown field %s",
+		"validate.items_type":               "group %s must be a list of items",
+		"validate.type":                     "invalid value: %s",
+		"validate.duplicate_module":         "module %s duplicates the item on line %d",
+		"validate.duplicate_module_group":   "module %s is also used by the item in group %s on line %d",
+		"validate.duplicate_filename":       "target file %s is also written by the item in group %s on line %d",
+		"validate.module_required":          "an item in group %s has no module",
+		"validate.filename_required":        "item %s has no filename",
+		"validate.filename_absolute":        "filename %s is an absolute path outside the output directory",
+		"validate.filename_escape":          "filename %s escapes the output directory",
+		"validate.extract_escape":           "extract dest %s escapes the output directory",
+		"validate.urls_required":            "item %s has no download URLs",
+		"validate.invalid_url":              "invalid download URL: %s",
+		"validate.invalid_strategy":         "unsupported mirror strategy: %s",
+		"cleanup.remove_partial_failed":     "failed to remove partial file %s: %w",
+		"cache.remove_failed":               "failed to remove cache file: %w",
+		"filter.invalid_pattern":            "invalid wildcard pattern: %s",
+		"lock.read_failed":                  "failed to read lock file: %w",
+		"lock.parse_failed":                 "failed to parse lock file %s: %w",
+		"lock.unsupported_version":          "unsupported lock file version %d (up to %d is supported)",
+		"lock.write_failed":                 "failed to write lock file: %w",
+		"lock.stat_failed":                  "failed to stat %s: %w",
+		"lock.item_missing":                 "lock file has no entry for %s (%s)",
+		"lock.item_incomplete":              "lock entry for %s is missing the URL or sha256",
+		"cache.lock_failed":                 "failed to lock the cache file, only in-process locking is used: %v",
+		"partial.lock_failed":               "    Failed to lock the partial download file, continuing without lock: %v",
+		"partial.locked_wait":               "    Another process is downloading %s, waiting for it to finish",
+		"item.timeout_stop":                 "    Item time limit (%v) exceeded, stopping download",
+		"extract.too_large":                 "extracted files exceed the size limit of %s",
+		"extract.keep_old_failed":           "    Warning: failed to keep old file %s: %v",
+		"item.no_urls":                      "item has no download URLs",
+		"lock.no_url_skip":                  "  Warning: download URL of %s is unknown, not writing a lock entry",
+		"release.response_too_large":        "GitHub API response exceeds the size limit (%d bytes): %s",
+		"extract.overwrites_archive":        "extracted file %s would overwrite the archive itself",
+		"item.extract_next":                 "    Failed to extract the downloaded archive, trying next source",
+	},
+}
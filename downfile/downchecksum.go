@@ -0,0 +1,362 @@
+package downfile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 支持的校验算法名称
+const (
+	AlgoSHA256 = "sha256"
+	AlgoSHA512 = "sha512"
+	AlgoMD5    = "md5"
+)
+
+// ItemChecksums 获取下载项配置的期望校验值（算法 -> 十六进制摘要）
+func ItemChecksums(item DownItem) map[string]string {
+	checksums := make(map[string]string)
+	if v := normalizeChecksum(item.SHA256); v != "" {
+		checksums[AlgoSHA256] = v
+	}
+	if v := normalizeChecksum(item.SHA512); v != "" {
+		checksums[AlgoSHA512] = v
+	}
+	if v := normalizeChecksum(item.MD5); v != "" {
+		checksums[AlgoMD5] = v
+	}
+	return checksums
+}
+
+// normalizeChecksum 规范化校验值（去除空白并转为小写）
+func normalizeChecksum(sum string) string {
+	return strings.ToLower(strings.TrimSpace(sum))
+}
+
+// newHash 根据算法名称创建哈希函数
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case AlgoSHA256:
+		return sha256.New(), nil
+	case AlgoSHA512:
+		return sha512.New(), nil
+	case AlgoMD5:
+		return md5.New(), nil
+	default:
+		return nil, msgError("checksum.unsupported_algo", algo)
+	}
+}
+
+// checksumVerifier 在写入数据流的同时计算哈希并校验
+// 总是计算SHA256，下载完成后记录到缓存中，不需要再次读取文件
+type checksumVerifier struct {
+	algos   []string // 需要校验的算法
+	hashers map[string]hash.Hash
+	expects map[string]string
+}
+
+// newChecksumVerifier 创建校验器，未配置任何校验值时只计算SHA256
+func newChecksumVerifier(checksums map[string]string) (*checksumVerifier, error) {
+	verifier := &checksumVerifier{
+		hashers: map[string]hash.Hash{AlgoSHA256: sha256.New()},
+		expects: make(map[string]string),
+	}
+	for algo, expect := range checksums {
+		if _, exists := verifier.hashers[algo]; !exists {
+			h, err := newHash(algo)
+			if err != nil {
+				return nil, err
+			}
+			verifier.hashers[algo] = h
+		}
+		verifier.algos = append(verifier.algos, algo)
+		verifier.expects[algo] = normalizeChecksum(expect)
+	}
+	// 保证校验顺序稳定，便于输出
+	sort.Strings(verifier.algos)
+	return verifier, nil
+}
+
+// Writer 返回同时写入所有哈希函数的Writer
+func (v *checksumVerifier) Writer() io.Writer {
+	writers := make([]io.Writer, 0, len(v.hashers))
+	for _, h := range v.hashers {
+		writers = append(writers, h)
+	}
+	return io.MultiWriter(writers...)
+}
+
+// Configured 是否配置了需要校验的校验值
+func (v *checksumVerifier) Configured() bool {
+	return len(v.algos) > 0
+}
+
+// SHA256 返回已写入数据的SHA256
+func (v *checksumVerifier) SHA256() string {
+	return hex.EncodeToString(v.hashers[AlgoSHA256].Sum(nil))
+}
+
+// Verify 校验计算结果与期望值是否一致
+func (v *checksumVerifier) Verify() error {
+	for _, algo := range v.algos {
+		actual := hex.EncodeToString(v.hashers[algo].Sum(nil))
+		if actual != v.expects[algo] {
+			return DownloadError{
+				Message: Msg("checksum.mismatch", algo, v.expects[algo], actual),
+				Type:    ErrChecksumMismatch,
+			}
+		}
+	}
+	return nil
+}
+
+// checksumManifest 校验清单（SHA256SUMS 或 <file>.sha256 格式）
+type checksumManifest struct {
+	Entries map[string]manifestEntry // 文件名 -> 校验值
+	Bare    *manifestEntry           // 仅包含摘要、不带文件名的校验值
+}
+
+// manifestEntry 校验清单中的单条记录
+type manifestEntry struct {
+	Algo string
+	Sum  string
+}
+
+// algoBySumLength 根据十六进制摘要长度推断校验算法
+func algoBySumLength(sum string) string {
+	if _, err := hex.DecodeString(sum); err != nil {
+		return ""
+	}
+	switch len(sum) {
+	case 32:
+		return AlgoMD5
+	case 64:
+		return AlgoSHA256
+	case 128:
+		return AlgoSHA512
+	}
+	return ""
+}
+
+// parseChecksumManifest 解析校验清单
+// 支持 GNU 格式 "<摘要>  [*]<文件名>"、BSD 格式 "SHA256 (<文件名>) = <摘要>" 以及仅包含摘要的单行格式
+func parseChecksumManifest(data []byte) *checksumManifest {
+	manifest := &checksumManifest{Entries: make(map[string]manifestEntry)}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var name, sum string
+		if open := strings.Index(line, " ("); open > 0 && strings.Contains(line, ") = ") {
+			// BSD 格式
+			closeIdx := strings.LastIndex(line, ") = ")
+			name = line[open+2 : closeIdx]
+			sum = line[closeIdx+4:]
+		} else {
+			fields := strings.Fields(line)
+			sum = fields[0]
+			if len(fields) > 1 {
+				name = strings.TrimPrefix(strings.TrimSpace(line[len(fields[0]):]), "*")
+			}
+		}
+
+		sum = normalizeChecksum(sum)
+		algo := algoBySumLength(sum)
+		if algo == "" {
+			continue
+		}
+
+		entry := manifestEntry{Algo: algo, Sum: sum}
+		if name == "" {
+			manifest.Bare = &entry
+			continue
+		}
+		name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+		manifest.Entries[name] = entry
+		// 同时以文件基础名索引，便于按下载文件名查找
+		if base := path.Base(name); base != name {
+			if _, exists := manifest.Entries[base]; !exists {
+				manifest.Entries[base] = entry
+			}
+		}
+	}
+	return manifest
+}
+
+// lookup 按候选文件名查找校验值，allowSingle 为 true 时允许使用清单中唯一的一条记录
+func (m *checksumManifest) lookup(names []string, allowSingle bool) (manifestEntry, bool) {
+	for _, name := range names {
+		if entry, ok := m.Entries[name]; ok {
+			return entry, true
+		}
+	}
+	if allowSingle {
+		if m.Bare != nil {
+			return *m.Bare, true
+		}
+		if len(m.Entries) == 1 {
+			for _, entry := range m.Entries {
+				return entry, true
+			}
+		}
+	}
+	return manifestEntry{}, false
+}
+
+// checksumManifests 校验清单缓存，同一清单在一次运行中只下载一次
+// 每个清单地址单独等待，下载较慢的清单不会阻塞使用其他清单的下载项
+type checksumManifests struct {
+	mu      sync.Mutex
+	fetches map[string]*manifestFetch
+}
+
+// manifestFetch 单个校验清单的下载结果，done 关闭后 manifest/err/aborted 可读
+type manifestFetch struct {
+	done     chan struct{}
+	manifest *checksumManifest
+	err      error
+	// aborted 发起下载的下载项 ctx 已结束（取消或超时），结果不代表清单本身的下载结果
+	aborted bool
+}
+
+// newChecksumManifests 创建校验清单缓存
+func newChecksumManifests() *checksumManifests {
+	return &checksumManifests{fetches: make(map[string]*manifestFetch)}
+}
+
+// get 获取校验清单，未缓存时使用 client 下载，同一清单正在下载时等待其结果
+// 下载失败的结果不缓存，之后的调用重新下载；
+// 发起下载的下载项 ctx 结束导致下载中止时，等待中的下载项使用自己的 ctx 重新下载
+func (c *checksumManifests) get(ctx context.Context, client *http.Client, manifestURL string) (*checksumManifest, error) {
+	for {
+		c.mu.Lock()
+		fetch, exists := c.fetches[manifestURL]
+		if !exists {
+			fetch = &manifestFetch{done: make(chan struct{})}
+			c.fetches[manifestURL] = fetch
+		}
+		c.mu.Unlock()
+
+		if !exists {
+			return c.fetch(ctx, client, manifestURL, fetch)
+		}
+
+		select {
+		case <-fetch.done:
+			if fetch.aborted && ctx.Err() == nil {
+				continue
+			}
+			return fetch.manifest, fetch.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fetch 下载校验清单并通知等待中的下载项，失败时移除缓存记录
+func (c *checksumManifests) fetch(ctx context.Context, client *http.Client, manifestURL string, fetch *manifestFetch) (*checksumManifest, error) {
+	fetch.manifest, fetch.err = fetchChecksumManifest(ctx, client, manifestURL)
+	if fetch.err != nil {
+		fetch.aborted = ctx.Err() != nil
+		c.mu.Lock()
+		delete(c.fetches, manifestURL)
+		c.mu.Unlock()
+	}
+	close(fetch.done)
+	return fetch.manifest, fetch.err
+}
+
+// fetchChecksumManifest 下载并解析校验清单
+func fetchChecksumManifest(ctx context.Context, client *http.Client, manifestURL string) (*checksumManifest, error) {
+	fetchURL := manifestURL
+	if strings.Contains(fetchURL, "github.com") && strings.Contains(fetchURL, "/blob/") {
+		fetchURL = ConvertGitHubURL(fetchURL)
+	}
+
+	resp, err := httpGet(ctx, client, fetchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxChecksumFileSize))
+	if err != nil {
+		return nil, msgError("checksum.read_manifest_failed", err)
+	}
+	return parseChecksumManifest(data), nil
+}
+
+// checksumCandidateNames 获取在校验清单中查找下载项时使用的候选文件名
+func checksumCandidateNames(item DownItem) []string {
+	names := []string{filepath.Base(item.FileName)}
+	for _, rawURL := range item.DownloadURLs {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		if base := path.Base(parsed.Path); base != "." && base != "/" {
+			names = append(names, base)
+		}
+	}
+	return names
+}
+
+// resolveItemChecksums 合并配置中的校验值与远程校验清单中的校验值
+// 配置文件中直接指定的校验值优先
+func resolveItemChecksums(ctx context.Context, client *http.Client, item DownItem, manifests *checksumManifests) (map[string]string, error) {
+	checksums := ItemChecksums(item)
+	names := checksumCandidateNames(item)
+
+	sources := []struct {
+		url         string
+		allowSingle bool
+	}{
+		{item.ChecksumURL, true},
+		{item.ChecksumsFile, false},
+	}
+
+	for _, source := range sources {
+		if source.url == "" {
+			continue
+		}
+
+		manifest, err := manifests.get(ctx, client, source.url)
+		if err != nil {
+			return nil, DownloadError{
+				Message: Msg("checksum.fetch_manifest_failed", source.url, err),
+				Type:    ErrChecksumUnavailable,
+			}
+		}
+
+		entry, ok := manifest.lookup(names, source.allowSingle)
+		if !ok {
+			return nil, DownloadError{
+				Message: Msg("checksum.not_in_manifest", source.url, filepath.Base(item.FileName)),
+				Type:    ErrChecksumUnavailable,
+			}
+		}
+
+		if _, exists := checksums[entry.Algo]; !exists {
+			checksums[entry.Algo] = entry.Sum
+		}
+	}
+
+	return checksums, nil
+}
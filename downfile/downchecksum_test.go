@@ -0,0 +1,320 @@
+package downfile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseChecksumManifest(t *testing.T) {
+	sumA := sha256Hex("a")
+	sumB := sha256Hex("b")
+	md5Sum := strings.Repeat("ab", 16)
+
+	tests := []struct {
+		name        string
+		data        string
+		lookup      []string
+		allowSingle bool
+		wantAlgo    string
+		wantSum     string
+		wantFound   bool
+	}{
+		{
+			name:      "GNU格式",
+			data:      sumA + "  a.mmdb\n" + sumB + "  b.mmdb\n",
+			lookup:    []string{"b.mmdb"},
+			wantAlgo:  AlgoSHA256,
+			wantSum:   sumB,
+			wantFound: true,
+		},
+		{
+			name:      "GNU二进制模式",
+			data:      sumA + " *a.mmdb\n",
+			lookup:    []string{"a.mmdb"},
+			wantAlgo:  AlgoSHA256,
+			wantSum:   sumA,
+			wantFound: true,
+		},
+		{
+			name:      "BSD格式",
+			data:      "SHA256 (a.mmdb) = " + strings.ToUpper(sumA) + "\n",
+			lookup:    []string{"a.mmdb"},
+			wantAlgo:  AlgoSHA256,
+			wantSum:   sumA,
+			wantFound: true,
+		},
+		{
+			name:      "带目录的文件名按基础名查找",
+			data:      sumA + "  ./dist/a.mmdb\n",
+			lookup:    []string{"a.mmdb"},
+			wantAlgo:  AlgoSHA256,
+			wantSum:   sumA,
+			wantFound: true,
+		},
+		{
+			name:      "按摘要长度识别MD5",
+			data:      md5Sum + "  a.txt\n",
+			lookup:    []string{"a.txt"},
+			wantAlgo:  AlgoMD5,
+			wantSum:   md5Sum,
+			wantFound: true,
+		},
+		{
+			name:        "仅包含摘要的sidecar",
+			data:        sumA + "\n",
+			lookup:      []string{"other.mmdb"},
+			allowSingle: true,
+			wantAlgo:    AlgoSHA256,
+			wantSum:     sumA,
+			wantFound:   true,
+		},
+		{
+			name:        "唯一记录允许作为sidecar",
+			data:        sumA + "  renamed.mmdb\n",
+			lookup:      []string{"a.mmdb"},
+			allowSingle: true,
+			wantAlgo:    AlgoSHA256,
+			wantSum:     sumA,
+			wantFound:   true,
+		},
+		{
+			name:   "清单中不存在",
+			data:   sumA + "  a.mmdb\n",
+			lookup: []string{"b.mmdb"},
+		},
+		{
+			name:   "忽略注释及无效摘要",
+			data:   "# " + sumA + "  a.mmdb\nnot-a-sum  a.mmdb\n",
+			lookup: []string{"a.mmdb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := parseChecksumManifest([]byte(tt.data))
+			entry, found := manifest.lookup(tt.lookup, tt.allowSingle)
+			if found != tt.wantFound {
+				t.Fatalf("found = %v, want %v", found, tt.wantFound)
+			}
+			if !found {
+				return
+			}
+			if entry.Algo != tt.wantAlgo || entry.Sum != tt.wantSum {
+				t.Errorf("entry = %+v, want %s:%s", entry, tt.wantAlgo, tt.wantSum)
+			}
+		})
+	}
+}
+
+func TestChecksumVerifier(t *testing.T) {
+	verifier, err := newChecksumVerifier(map[string]string{AlgoSHA256: strings.ToUpper(sha256Hex("hello"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.Writer().Write([]byte("hello"))
+	if err := verifier.Verify(); err != nil {
+		t.Errorf("Verify() = %v, want nil", err)
+	}
+
+	verifier, _ = newChecksumVerifier(map[string]string{AlgoSHA256: sha256Hex("hello")})
+	verifier.Writer().Write([]byte("world"))
+	var downloadErr DownloadError
+	if err := verifier.Verify(); !errors.As(err, &downloadErr) || downloadErr.Type != ErrChecksumMismatch {
+		t.Errorf("Verify() = %v, want %s", err, ErrChecksumMismatch)
+	}
+
+	if _, err := newChecksumVerifier(map[string]string{"crc32": "00"}); err == nil {
+		t.Error("unsupported algorithm accepted")
+	}
+}
+
+func TestResolveItemChecksums(t *testing.T) {
+	sumA := sha256Hex("a")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sumA + "  a.mmdb\n"))
+	})
+	mux.HandleFunc("/a.mmdb.sha256", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sumA + "\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		item     DownItem
+		want     string
+		wantType string
+	}{
+		{
+			name: "校验清单",
+			item: DownItem{FileName: "a.mmdb", ChecksumsFile: server.URL + "/SHA256SUMS"},
+			want: sumA,
+		},
+		{
+			name: "sidecar文件",
+			item: DownItem{FileName: "data/a.mmdb", ChecksumURL: server.URL + "/a.mmdb.sha256"},
+			want: sumA,
+		},
+		{
+			name: "配置中的校验值优先",
+			item: DownItem{FileName: "a.mmdb", SHA256: sha256Hex("b"), ChecksumsFile: server.URL + "/SHA256SUMS"},
+			want: sha256Hex("b"),
+		},
+		{
+			name:     "清单中没有下载项",
+			item:     DownItem{FileName: "b.mmdb", ChecksumsFile: server.URL + "/SHA256SUMS"},
+			wantType: ErrChecksumUnavailable,
+		},
+		{
+			name:     "清单下载失败",
+			item:     DownItem{FileName: "a.mmdb", ChecksumURL: server.URL + "/missing.sha256"},
+			wantType: ErrChecksumUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksums, err := resolveItemChecksums(context.Background(), server.Client(), tt.item, newChecksumManifests())
+			if tt.wantType != "" {
+				var downloadErr DownloadError
+				if !errors.As(err, &downloadErr) || downloadErr.Type != tt.wantType {
+					t.Fatalf("err = %v, want %s", err, tt.wantType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checksums[AlgoSHA256] != tt.want {
+				t.Errorf("sha256 = %q, want %q", checksums[AlgoSHA256], tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksumManifestsFetchOncePerURL(t *testing.T) {
+	sumA := sha256Hex("a")
+	release := make(chan struct{})
+	var slowFetches, fastFetches atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		slowFetches.Add(1)
+		<-release
+		w.Write([]byte(sumA + "  a.mmdb\n"))
+	})
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		fastFetches.Add(1)
+		w.Write([]byte(sumA + "  a.mmdb\n"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer close(release)
+
+	manifests := newChecksumManifests()
+	ctx := context.Background()
+
+	// 等待同一个较慢清单的调用不应阻塞其他清单
+	slowDone := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := manifests.get(ctx, server.Client(), server.URL+"/slow")
+			slowDone <- err
+		}()
+	}
+
+	fastDone := make(chan error, 1)
+	go func() {
+		_, err := manifests.get(ctx, server.Client(), server.URL+"/fast")
+		fastDone <- err
+	}()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast manifest blocked by slow manifest")
+	}
+
+	release <- struct{}{}
+	for i := 0; i < 3; i++ {
+		if err := <-slowDone; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := manifests.get(ctx, server.Client(), server.URL+"/fast"); err != nil {
+		t.Fatal(err)
+	}
+	if n := slowFetches.Load(); n != 1 {
+		t.Errorf("slow manifest fetched %d times, want 1", n)
+	}
+	if n := fastFetches.Load(); n != 1 {
+		t.Errorf("fast manifest fetched %d times, want 1", n)
+	}
+}
+
+func TestChecksumManifestsRetryAfterAbortedFetch(t *testing.T) {
+	sumA := sha256Hex("a")
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求一直等待到客户端取消
+		if fetches.Add(1) == 1 {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(sumA + "  a.mmdb\n"))
+	}))
+	defer server.Close()
+
+	manifests := newChecksumManifests()
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := manifests.get(firstCtx, server.Client(), server.URL)
+		firstDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan error, 1)
+	go func() {
+		manifest, err := manifests.get(context.Background(), server.Client(), server.URL)
+		if err == nil && manifest.Entries["a.mmdb"].Sum != sumA {
+			err = errors.New("manifest entry not found")
+		}
+		waiterDone <- err
+	}()
+	// 等待第二个调用开始等待第一次下载的结果
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-firstDone; err == nil {
+		t.Error("cancelled fetch returned no error")
+	}
+	select {
+	case err := <-waiterDone:
+		if err != nil {
+			t.Errorf("waiter failed with another item's context error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiter blocked after the shared fetch was cancelled")
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("manifest fetched %d times, want 2", n)
+	}
+}
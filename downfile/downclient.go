@@ -1,7 +1,6 @@
 package downfile
 
 import (
-	"fmt"
 	"net"
 	"net/http"
 	"net/url"
@@ -36,7 +35,7 @@ func CreateHTTPClient(config *ClientConfig) (*http.Client, error) {
 	if config.ProxyURL != "" {
 		proxyURL, err := url.Parse(config.ProxyURL)
 		if err != nil {
-			return nil, fmt.Errorf("解析代理URL失败: %w", err)
+			return nil, msgError("client.proxy_parse_failed", err)
 		}
 		proxyFunc = http.ProxyURL(proxyURL)
 	} else {
@@ -0,0 +1,203 @@
+package downfile
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置文件格式版本
+const (
+	ConfigVersionLegacy = 1 // 旧版格式，顶层键为配置组
+	ConfigVersion       = 2 // 当前格式，包含 settings、defaults 及 groups
+)
+
+// Settings 配置文件中的全局设置，未配置（零值）的字段使用命令行参数的默认值
+type Settings struct {
+	OutputDir      string        `yaml:"output-dir"`      // 下载文件保存目录
+	Proxy          string        `yaml:"proxy"`           // 代理URL
+	Retries        int           `yaml:"retries"`         // 下载失败重试次数
+	ConnectTimeout int           `yaml:"connect-timeout"` // 连接超时时间（秒）
+	IdleTimeout    int           `yaml:"idle-timeout"`    // 空闲超时时间（秒）
+	TotalTimeout   time.Duration `yaml:"total-timeout"`   // 整个运行的最长时间
+	CacheExpire    float64       `yaml:"cache-expire"`    // 缓存过期时间（小时）
+	Concurrency    int           `yaml:"concurrency"`     // 并发下载数量
+	KeepOld        *bool         `yaml:"keep-old"`        // 是否保留旧文件
+	MirrorStrategy string        `yaml:"mirror-strategy"` // 默认下载源排序策略
+	Segments       int           `yaml:"segments"`        // 分段下载的并行连接数
+}
+
+// configSchema 当前版本的配置文件结构
+type configSchema struct {
+	Version      int                  `yaml:"version"`
+	Settings     Settings             `yaml:"settings"`
+	Defaults     yaml.Node            `yaml:"defaults"`
+	RewriteRules []RewriteRule        `yaml:"rewrite-rules"`
+	Groups       map[string]yaml.Node `yaml:"groups"`
+}
+
+// groupSchema 带有组内默认值的配置组，配置组也可以直接写成下载项列表
+type groupSchema struct {
+	Defaults yaml.Node   `yaml:"defaults"`
+	Items    []yaml.Node `yaml:"items"`
+}
+
+// LoadConfigFile 加载配置文件
+// 顶层 version 为标量或 groups 为映射时按当前格式解析，否则按旧版格式解析（顶层的 rewrite-rules 为改写规则，其余键为配置组）
+func LoadConfigFile(filename string) (*ConfigFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, msgError("config.read_failed", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig 解析配置文件内容
+func ParseConfig(data []byte) (*ConfigFile, error) {
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, msgError("config.parse_failed", err)
+	}
+
+	var config *ConfigFile
+	var err error
+	version, groups := nodes["version"], nodes["groups"]
+	if isSchemaConfig(&version, &groups) {
+		config, err = parseSchemaConfig(data)
+	} else {
+		config, err = parseLegacyConfig(nodes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CompileRewriteRules(config.RewriteRules); err != nil {
+		return nil, msgError("config.invalid_rewrite_rule", err)
+	}
+	return config, nil
+}
+
+// isSchemaConfig 判断配置文件是否为当前格式：顶层 version 为标量或 groups 为映射
+// 旧版格式中名为 version 或 groups 的配置组是下载项列表，仍按旧版格式解析
+func isSchemaConfig(version, groups *yaml.Node) bool {
+	return (version != nil && version.Kind == yaml.ScalarNode) ||
+		(groups != nil && groups.Kind == yaml.MappingNode)
+}
+
+// parseLegacyConfig 解析旧版格式的配置文件
+func parseLegacyConfig(nodes map[string]yaml.Node) (*ConfigFile, error) {
+	config := &ConfigFile{Version: ConfigVersionLegacy, Groups: make(DownConfig)}
+	for key, node := range nodes {
+		if key == RewriteRulesKey {
+			if err := node.Decode(&config.RewriteRules); err != nil {
+				return nil, msgError("config.parse_failed", err)
+			}
+			continue
+		}
+		var items []DownItem
+		if err := node.Decode(&items); err != nil {
+			return nil, msgError("config.parse_failed", err)
+		}
+		config.Groups[key] = items
+	}
+	return config, nil
+}
+
+// parseSchemaConfig 解析当前格式的配置文件，defaults 应用于所有下载项，配置组的 defaults 覆盖全局 defaults
+func parseSchemaConfig(data []byte) (*ConfigFile, error) {
+	var schema configSchema
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, msgError("config.parse_failed", err)
+	}
+	if schema.Version == 0 {
+		schema.Version = ConfigVersion
+	}
+	if schema.Version > ConfigVersion {
+		return nil, msgError("config.unsupported_version", schema.Version, ConfigVersion)
+	}
+
+	var defaults DownItem
+	if err := decodeDefaults(&schema.Defaults, &defaults); err != nil {
+		return nil, err
+	}
+
+	config := &ConfigFile{
+		Version:      schema.Version,
+		Settings:     schema.Settings,
+		Groups:       make(DownConfig),
+		RewriteRules: schema.RewriteRules,
+	}
+	for groupName, node := range schema.Groups {
+		items, err := decodeGroup(&node, defaults)
+		if err != nil {
+			return nil, msgError("config.group_invalid", groupName, err)
+		}
+		config.Groups[groupName] = items
+	}
+	return config, nil
+}
+
+// decodeGroup 解析配置组，配置组可以是下载项列表，也可以是包含 defaults 及 items 的映射
+func decodeGroup(node *yaml.Node, defaults DownItem) ([]DownItem, error) {
+	itemNodes := node.Content
+	if node.Kind == yaml.MappingNode {
+		var group groupSchema
+		if err := node.Decode(&group); err != nil {
+			return nil, err
+		}
+		if err := decodeDefaults(&group.Defaults, &defaults); err != nil {
+			return nil, err
+		}
+		itemNodes = make([]*yaml.Node, 0, len(group.Items))
+		for i := range group.Items {
+			itemNodes = append(itemNodes, &group.Items[i])
+		}
+	} else if node.Kind != yaml.SequenceNode {
+		return nil, msgError("config.group_type", node.Line)
+	}
+
+	items := make([]DownItem, 0, len(itemNodes))
+	for _, itemNode := range itemNodes {
+		// 在默认值的副本上解析下载项，下载项中配置的字段覆盖默认值
+		item := copyDownItem(defaults)
+		if err := itemNode.Decode(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// decodeDefaults 在 defaults 上解析默认值节点，节点为空时不做修改
+func decodeDefaults(node *yaml.Node, defaults *DownItem) error {
+	if node.Kind == 0 {
+		return nil
+	}
+	merged := copyDownItem(*defaults)
+	if err := node.Decode(&merged); err != nil {
+		return msgError("config.defaults_invalid", err)
+	}
+	*defaults = merged
+	return nil
+}
+
+// copyDownItem 复制下载项，切片及解压配置单独复制，避免下载项之间或与默认值共用底层数据
+func copyDownItem(item DownItem) DownItem {
+	item.DownloadURLs = copyStrings(item.DownloadURLs)
+	item.Tags = copyStrings(item.Tags)
+	if item.Extract != nil {
+		extract := *item.Extract
+		extract.Files = copyStrings(extract.Files)
+		item.Extract = &extract
+	}
+	return item
+}
+
+// copyStrings 复制字符串切片，nil 仍返回 nil
+func copyStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
@@ -0,0 +1,186 @@
+package downfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigDefaults(t *testing.T) {
+	data := `
+version: 2
+defaults:
+  enable: true
+  keep-updated: true
+  tags: [base]
+  min-speed: 1024
+groups:
+  plain:
+    - module: a
+      filename: a.bin
+      download-urls: [https://example.com/a.bin]
+  geoip:
+    defaults:
+      min-speed: 10240
+      checksums-file: https://example.com/SHA256SUMS
+      extract:
+        files: ["*.mmdb"]
+    items:
+      - module: b
+        filename: b.bin
+        download-urls: [https://example.com/b.bin]
+      - module: c
+        filename: c.bin
+        download-urls: [https://example.com/c.bin]
+        enable: false
+        min-speed: 1
+        tags: [extra]
+`
+	config, err := ParseConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != ConfigVersion {
+		t.Errorf("Version = %d, want %d", config.Version, ConfigVersion)
+	}
+
+	tests := []struct {
+		name          string
+		group         string
+		index         int
+		enable        bool
+		minSpeed      float64
+		checksumsFile string
+		tags          []string
+		extract       bool
+	}{
+		{name: "全局默认值", group: "plain", index: 0, enable: true, minSpeed: 1024, tags: []string{"base"}},
+		{name: "组内默认值覆盖全局默认值", group: "geoip", index: 0, enable: true, minSpeed: 10240, checksumsFile: "https://example.com/SHA256SUMS", tags: []string{"base"}, extract: true},
+		{name: "下载项覆盖默认值", group: "geoip", index: 1, enable: false, minSpeed: 1, checksumsFile: "https://example.com/SHA256SUMS", tags: []string{"extra"}, extract: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := config.Groups[tt.group]
+			if len(items) <= tt.index {
+				t.Fatalf("group %s has %d items", tt.group, len(items))
+			}
+			item := items[tt.index]
+			if !item.KeepUpdated || item.Enable != tt.enable || item.MinSpeed != tt.minSpeed {
+				t.Errorf("keep-updated=%v enable=%v min-speed=%v, want true/%v/%v", item.KeepUpdated, item.Enable, item.MinSpeed, tt.enable, tt.minSpeed)
+			}
+			if item.ChecksumsFile != tt.checksumsFile {
+				t.Errorf("checksums-file = %q, want %q", item.ChecksumsFile, tt.checksumsFile)
+			}
+			if !reflect.DeepEqual(item.Tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", item.Tags, tt.tags)
+			}
+			if (item.Extract != nil) != tt.extract {
+				t.Errorf("extract = %v, want set=%v", item.Extract, tt.extract)
+			}
+		})
+	}
+
+	// 同组下载项的解压配置互不共用
+	b, c := config.Groups["geoip"][0], config.Groups["geoip"][1]
+	if b.Extract == c.Extract {
+		t.Error("items share the same extract config")
+	}
+}
+
+func TestParseConfigFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		groups  []string
+		wantErr bool
+	}{
+		{
+			name:    "旧版格式",
+			data:    "geoip:\n  - module: a\n    download-urls: [https://example.com/a.bin]\n",
+			version: ConfigVersionLegacy,
+			groups:  []string{"geoip"},
+		},
+		{
+			name:    "旧版格式中名为version的配置组",
+			data:    "version:\n  - module: a\n    download-urls: [https://example.com/a.bin]\n",
+			version: ConfigVersionLegacy,
+			groups:  []string{"version"},
+		},
+		{
+			name:    "旧版格式中名为groups的配置组",
+			data:    "groups:\n  - module: a\n    download-urls: [https://example.com/a.bin]\nother:\n  - module: b\n",
+			version: ConfigVersionLegacy,
+			groups:  []string{"groups", "other"},
+		},
+		{
+			name:    "当前格式省略version",
+			data:    "groups:\n  geoip:\n    - module: a\n",
+			version: ConfigVersion,
+			groups:  []string{"geoip"},
+		},
+		{
+			name:    "当前格式包含名为version的配置组",
+			data:    "version: 2\ngroups:\n  version:\n    - module: a\n",
+			version: ConfigVersion,
+			groups:  []string{"version"},
+		},
+		{
+			name:    "不支持的版本",
+			data:    "version: 3\ngroups: {}\n",
+			wantErr: true,
+		},
+		{
+			name:    "配置组类型错误",
+			data:    "version: 2\ngroups:\n  geoip: abc\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseConfig() accepted %q", tt.data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Version != tt.version {
+				t.Errorf("Version = %d, want %d", config.Version, tt.version)
+			}
+			if len(config.Groups) != len(tt.groups) {
+				t.Errorf("groups = %v, want %v", config.Groups, tt.groups)
+			}
+			for _, group := range tt.groups {
+				if len(config.Groups[group]) == 0 {
+					t.Errorf("group %q missing or empty", group)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyDownItem(t *testing.T) {
+	item := DownItem{
+		DownloadURLs: []string{"https://example.com/a.bin"},
+		Tags:         []string{"a"},
+		Extract:      &ExtractConfig{Files: []string{"*.mmdb"}},
+	}
+	copied := copyDownItem(item)
+	copied.DownloadURLs[0] = "changed"
+	copied.Tags[0] = "changed"
+	copied.Extract.Files[0] = "changed"
+	copied.Extract.Dest = "changed"
+
+	if item.DownloadURLs[0] != "https://example.com/a.bin" || item.Tags[0] != "a" {
+		t.Errorf("copy shares slices with the original: %v %v", item.DownloadURLs, item.Tags)
+	}
+	if item.Extract.Files[0] != "*.mmdb" || item.Extract.Dest != "" {
+		t.Errorf("copy shares the extract config with the original: %+v", item.Extract)
+	}
+	if empty := copyDownItem(DownItem{}); empty.DownloadURLs != nil || empty.Tags != nil || empty.Extract != nil {
+		t.Errorf("copyDownItem(DownItem{}) = %+v, want nil fields", empty)
+	}
+}
@@ -0,0 +1,137 @@
+package downfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// progressBoard 终端输出协调器，统一绘制多个下载项的进度行并穿插普通日志输出，避免并发输出错乱
+type progressBoard struct {
+	mu       sync.Mutex
+	out      io.Writer
+	multi    bool            // 是否使用多行进度视图
+	terminal bool            // 输出是否为终端
+	entries  []ProgressEvent // 当前正在显示的下载进度
+	lines    int             // 当前已绘制的进度行数
+}
+
+// console 全局终端输出协调器
+var console = newProgressBoard(os.Stdout)
+
+// newProgressBoard 创建输出到 out 的终端输出协调器
+func newProgressBoard(out io.Writer) *progressBoard {
+	board := &progressBoard{out: out}
+	if f, ok := out.(*os.File); ok {
+		board.terminal = isTerminal(f)
+	}
+	return board
+}
+
+// isTerminal 判断文件是否为终端设备
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetMultiLineProgress 设置是否使用多行进度视图（并发下载时使用）
+func SetMultiLineProgress(enable bool) {
+	console.setMulti(enable)
+}
+
+// setMulti 设置是否使用多行进度视图
+func (b *progressBoard) setMulti(enable bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	b.multi = enable
+	b.draw()
+}
+
+// Printf 输出一条日志，先擦除进度行，输出后重新绘制
+func (b *progressBoard) Printf(format string, args ...any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	fmt.Fprintf(b.out, format, args...)
+	b.draw()
+}
+
+// add 注册下载进度，进度行在下一次刷新时绘制
+func (b *progressBoard) add(event ProgressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range b.entries {
+		if e.ID == event.ID {
+			return
+		}
+	}
+	b.entries = append(b.entries, event)
+}
+
+// update 更新下载进度并重新绘制所有进度行
+func (b *progressBoard) update(event ProgressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, e := range b.entries {
+		if e.ID == event.ID {
+			b.entries[i] = event
+			b.clear()
+			b.draw()
+			return
+		}
+	}
+}
+
+// remove 移除下载进度
+func (b *progressBoard) remove(id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, e := range b.entries {
+		if e.ID == id {
+			b.clear()
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			b.draw()
+			return
+		}
+	}
+}
+
+// clear 擦除已绘制的进度行（调用方需持有锁）
+func (b *progressBoard) clear() {
+	if b.lines == 0 {
+		return
+	}
+	if b.multi {
+		// 光标上移到第一条进度行并清除到屏幕末尾
+		fmt.Fprintf(b.out, "\033[%dA\033[J", b.lines)
+	} else {
+		fmt.Fprint(b.out, "\r"+strings.Repeat(" ", 132)+"\r")
+	}
+	b.lines = 0
+}
+
+// draw 绘制所有进度行（调用方需持有锁）
+func (b *progressBoard) draw() {
+	if len(b.entries) == 0 {
+		return
+	}
+	if b.multi {
+		// 非终端输出时不绘制多行进度，避免控制字符污染日志
+		if !b.terminal {
+			return
+		}
+		for _, e := range b.entries {
+			fmt.Fprintf(b.out, "    [%s] %s\n", e.Name, formatProgressLine(e))
+		}
+		b.lines = len(b.entries)
+	} else {
+		fmt.Fprintf(b.out, "\r    %s", formatProgressLine(b.entries[len(b.entries)-1]))
+		b.lines = 1
+	}
+}
@@ -0,0 +1,78 @@
+package downfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testBoard 创建输出到缓冲区的终端输出协调器
+func testBoard(terminal, multi bool) (*progressBoard, *bytes.Buffer) {
+	var buf bytes.Buffer
+	board := newProgressBoard(&buf)
+	board.terminal = terminal
+	board.multi = multi
+	return board, &buf
+}
+
+func TestProgressBoardMultiLine(t *testing.T) {
+	board, buf := testBoard(true, true)
+	a := ProgressEvent{ID: 1, Name: "a.bin", Downloaded: 10, Total: 100}
+	b := ProgressEvent{ID: 2, Name: "b.bin", Downloaded: 20, Total: 100}
+	board.add(a)
+	board.add(b)
+	board.add(a) // 重复注册不增加进度行
+
+	board.update(a)
+	if got, want := buf.String(), "    [a.bin] "+formatProgressLine(a)+"\n    [b.bin] "+formatProgressLine(b)+"\n"; got != want {
+		t.Fatalf("first draw = %q, want %q", got, want)
+	}
+
+	// 输出日志时先擦除所有进度行，再在日志之后重新绘制
+	buf.Reset()
+	board.Printf("log line\n")
+	if got := buf.String(); !strings.HasPrefix(got, "\033[2A\033[J") || !strings.Contains(got, "log line\n    [a.bin]") {
+		t.Errorf("Printf() output = %q, want cleared progress, log line and redrawn progress", got)
+	}
+
+	// 下载结束后只保留其他下载项的进度行
+	buf.Reset()
+	board.remove(1)
+	if got, want := buf.String(), "\033[2A\033[J    [b.bin] "+formatProgressLine(b)+"\n"; got != want {
+		t.Errorf("remove() output = %q, want %q", got, want)
+	}
+	board.remove(2)
+	if board.lines != 0 || len(board.entries) != 0 {
+		t.Errorf("lines = %d, entries = %d after removing all, want 0", board.lines, len(board.entries))
+	}
+}
+
+func TestProgressBoardSingleLine(t *testing.T) {
+	board, buf := testBoard(true, false)
+	a := ProgressEvent{ID: 1, Name: "a.bin", Downloaded: 10, Total: 100}
+	board.add(a)
+	board.update(a)
+	if got, want := buf.String(), "\r    "+formatProgressLine(a); got != want {
+		t.Fatalf("draw = %q, want %q", got, want)
+	}
+
+	// 单行进度在输出日志前用空格覆盖
+	buf.Reset()
+	board.Printf("log line\n")
+	if got, want := buf.String(), "\r"+strings.Repeat(" ", 132)+"\rlog line\n\r    "+formatProgressLine(a); got != want {
+		t.Errorf("Printf() output = %q, want %q", got, want)
+	}
+}
+
+func TestProgressBoardNotTerminal(t *testing.T) {
+	// 非终端输出时多行视图不绘制进度行，日志不包含控制字符
+	board, buf := testBoard(false, true)
+	a := ProgressEvent{ID: 1, Name: "a.bin", Downloaded: 10, Total: 100}
+	board.add(a)
+	board.update(a)
+	board.Printf("log line\n")
+	board.remove(1)
+	if got := buf.String(); got != "log line\n" {
+		t.Errorf("output = %q, want only the log line", got)
+	}
+}
@@ -0,0 +1,554 @@
+package downfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// ExtractConfig 下载完成后的解压配置
+type ExtractConfig struct {
+	Type    string   `yaml:"type"`    // 压缩包类型: auto|zip|tar|tar.gz|tar.xz|tar.bz2|gz|xz|bz2，默认auto自动识别
+	Files   []string `yaml:"files"`   // 需要保留的成员路径（支持通配符），为空时保留全部
+	Dest    string   `yaml:"dest"`    // 解压目标目录，相对路径基于下载目录，默认为压缩包所在目录
+	Flatten bool     `yaml:"flatten"` // 是否去掉成员路径中的目录层级
+}
+
+// 压缩包类型
+const (
+	ArchiveAuto  = "auto"
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveTarXz = "tar.xz"
+	ArchiveTarBz = "tar.bz2"
+	ArchiveGz    = "gz"
+	ArchiveXz    = "xz"
+	ArchiveBz2   = "bz2"
+)
+
+// ExtractMarkerSuffix 解压记录文件后缀，记录上次从压缩包解压出的文件
+const ExtractMarkerSuffix = ".extracted.json"
+
+// extractMarker 解压记录
+type extractMarker struct {
+	Files       []string  `json:"files"`        // 解压出的文件路径
+	ExtractedAt time.Time `json:"extracted_at"` // 解压时间
+}
+
+// archiveSuffixes 根据文件后缀识别压缩包类型（按匹配优先级排列）
+var archiveSuffixes = []struct {
+	suffix string
+	kind   string
+}{
+	{".tar.gz", ArchiveTarGz},
+	{".tgz", ArchiveTarGz},
+	{".tar.xz", ArchiveTarXz},
+	{".txz", ArchiveTarXz},
+	{".tar.bz2", ArchiveTarBz},
+	{".tbz2", ArchiveTarBz},
+	{".tar", ArchiveTar},
+	{".zip", ArchiveZip},
+	{".gz", ArchiveGz},
+	{".xz", ArchiveXz},
+	{".bz2", ArchiveBz2},
+}
+
+// extractDestDir 获取解压目标目录
+func extractDestDir(config *ExtractConfig, storePath, downloadDir string) string {
+	if config.Dest == "" {
+		return filepath.Dir(storePath)
+	}
+	return GetItemFilePath(config.Dest, downloadDir)
+}
+
+// samePath 判断两个路径是否指向同一位置（按绝对路径比较）
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// detectArchiveType 识别压缩包类型，优先使用文件名 name 的后缀，无法识别时读取文件头
+func detectArchiveType(archivePath, name string) (string, error) {
+	lowerName := strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(lowerName, s.suffix) {
+			return s.kind, nil
+		}
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, 6)
+	n, _ := io.ReadFull(file, header)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return ArchiveZip, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return compressedTarType(archivePath, ArchiveGz, ArchiveTarGz)
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return compressedTarType(archivePath, ArchiveBz2, ArchiveTarBz)
+	case bytes.HasPrefix(header, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return compressedTarType(archivePath, ArchiveXz, ArchiveTarXz)
+	}
+
+	// 未压缩的tar文件在257字节处包含 ustar 标识
+	if _, err := file.Seek(0, io.SeekStart); err == nil && isTarStream(file) {
+		return ArchiveTar, nil
+	}
+	return "", msgError("extract.unknown_type", name)
+}
+
+// compressedTarType 判断单文件压缩流解压后是否为tar
+func compressedTarType(archivePath, streamType, tarType string) (string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	reader, err := decompressReader(file, streamType)
+	if err != nil {
+		return "", err
+	}
+	if isTarStream(reader) {
+		return tarType, nil
+	}
+	return streamType, nil
+}
+
+// isTarStream 检查数据流开头是否为tar格式
+func isTarStream(r io.Reader) bool {
+	block := make([]byte, 512)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return false
+	}
+	return bytes.HasPrefix(block[257:], []byte("ustar"))
+}
+
+// decompressReader 创建单文件压缩流的解压Reader
+func decompressReader(r io.Reader, streamType string) (io.Reader, error) {
+	switch streamType {
+	case ArchiveGz:
+		return gzip.NewReader(r)
+	case ArchiveBz2:
+		return bzip2.NewReader(bufio.NewReader(r)), nil
+	case ArchiveXz:
+		return xz.NewReader(bufio.NewReader(r))
+	default:
+		return r, nil
+	}
+}
+
+// safeMemberPath 规范化压缩包成员路径，拒绝绝对路径及跳出目标目录的路径
+func safeMemberPath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", msgError("extract.absolute_member", name)
+	}
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", msgError("extract.member_escape", name)
+	}
+	return cleaned, nil
+}
+
+// matchMember 判断成员是否需要保留，不含路径分隔符的模式同时匹配文件名
+func matchMember(patterns []string, memberPath string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, memberPath); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(memberPath)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// archiveExtractor 将压缩包成员写入临时目录
+type archiveExtractor struct {
+	config      *ExtractConfig
+	stageDir    string
+	archiveName string          // 压缩包文件名，用于单文件压缩包的输出文件名
+	inPlace     bool            // 是否解压到压缩包所在目录
+	outputs     map[string]bool // 相对目标目录的输出路径
+	written     int64           // 已解压的字节数
+}
+
+// extractMember 写入单个成员文件
+func (e *archiveExtractor) extractMember(name string, r io.Reader) error {
+	memberPath, err := safeMemberPath(name)
+	if err != nil {
+		return err
+	}
+	if !matchMember(e.config.Files, memberPath) {
+		return nil
+	}
+
+	outputPath := memberPath
+	if e.config.Flatten {
+		outputPath = path.Base(memberPath)
+	}
+	if e.outputs[outputPath] {
+		return msgError("extract.duplicate_output", outputPath)
+	}
+	// 解压到压缩包所在目录时，不允许输出文件覆盖压缩包本身（如gzip头中记录的原始文件名与压缩包同名）
+	if e.inPlace && outputPath == e.archiveName {
+		return msgError("extract.overwrites_archive", name)
+	}
+
+	stagePath := filepath.Join(e.stageDir, filepath.FromSlash(outputPath))
+	// 再次确认写入位置在临时目录内
+	if rel, err := filepath.Rel(e.stageDir, stagePath); err != nil || strings.HasPrefix(rel, "..") {
+		return msgError("extract.member_escape", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(stagePath), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(stagePath)
+	if err != nil {
+		return err
+	}
+	if err := e.copyMember(out, r); err != nil {
+		out.Close()
+		return msgError("extract.member_failed", name, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	e.outputs[outputPath] = true
+	return nil
+}
+
+// copyMember 复制成员内容，解压总大小超过 MaxExtractSize 时返回错误
+func (e *archiveExtractor) copyMember(w io.Writer, r io.Reader) error {
+	if MaxExtractSize <= 0 {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	remaining := MaxExtractSize - e.written
+	n, err := io.Copy(w, io.LimitReader(r, remaining+1))
+	e.written += n
+	if err != nil {
+		return err
+	}
+	if n > remaining {
+		return msgError("extract.too_large", FormatSize(MaxExtractSize))
+	}
+	return nil
+}
+
+// extractTar 解压tar数据流
+func (e *archiveExtractor) extractTar(r io.Reader) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return msgError("extract.read_tar_failed", err)
+		}
+		// 只解压普通文件，忽略目录、链接等其他类型
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if err := e.extractMember(header.Name, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip 解压zip文件
+func (e *archiveExtractor) extractZip(archivePath string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return msgError("extract.read_zip_failed", err)
+	}
+	defer zipReader.Close()
+
+	for _, file := range zipReader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return msgError("extract.read_zip_member_failed", file.Name, err)
+		}
+		err = e.extractMember(file.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractArchive 解压下载完成的压缩包，storePath 为压缩包的保存路径
+// archivePath 可以是尚未替换 storePath 的临时文件，压缩包类型及单文件压缩包的输出文件名按 storePath 的文件名确定
+// 先解压到目标目录下的临时目录，全部成功后再替换目标文件，keepOld 为 true 时备份为 .old
+func extractArchive(config *ExtractConfig, archivePath, storePath, destDir string, keepOld bool, log MsgLogger) ([]string, error) {
+	archiveName := filepath.Base(storePath)
+	archiveType := strings.ToLower(config.Type)
+	if archiveType == "" || archiveType == ArchiveAuto {
+		detected, err := detectArchiveType(archivePath, archiveName)
+		if err != nil {
+			return nil, err
+		}
+		archiveType = detected
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, msgError("extract.mkdir_failed", err)
+	}
+	stageDir, err := os.MkdirTemp(destDir, ".extract-*")
+	if err != nil {
+		return nil, msgError("extract.mktemp_failed", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	extractor := &archiveExtractor{
+		config:      config,
+		stageDir:    stageDir,
+		archiveName: archiveName,
+		inPlace:     samePath(destDir, filepath.Dir(storePath)),
+		outputs:     make(map[string]bool),
+	}
+
+	switch archiveType {
+	case ArchiveZip:
+		err = extractor.extractZip(archivePath)
+	case ArchiveTar, ArchiveTarGz, ArchiveTarXz, ArchiveTarBz, ArchiveGz, ArchiveXz, ArchiveBz2:
+		err = extractor.extractStream(archivePath, archiveType)
+	default:
+		err = msgError("extract.unsupported_type", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(extractor.outputs) == 0 {
+		return nil, msgError("extract.no_match")
+	}
+
+	// 全部解压成功后替换目标文件
+	outputPaths := make([]string, 0, len(extractor.outputs))
+	for outputPath := range extractor.outputs {
+		outputPaths = append(outputPaths, outputPath)
+	}
+	sort.Strings(outputPaths)
+	return commitOutputs(stageDir, destDir, outputPaths, keepOld, log)
+}
+
+// placedOutput 已替换的目标文件及被替换的旧文件
+type placedOutput struct {
+	dest   string
+	backup string // 旧文件的临时备份路径，目标文件原本不存在时为空
+}
+
+// commitOutputs 使用临时目录中的文件替换目标文件，任何一个替换失败时恢复已替换的文件，目标目录不会出现新旧文件混合
+// 替换过程中旧文件先移动到目标目录下的备份临时目录，全部替换成功后 keepOld 为 true 时保留为 .old，否则删除
+func commitOutputs(stageDir, destDir string, outputPaths []string, keepOld bool, log MsgLogger) ([]string, error) {
+	backupDir, err := os.MkdirTemp(destDir, ".extract-old-*")
+	if err != nil {
+		return nil, msgError("extract.mktemp_failed", err)
+	}
+	defer os.RemoveAll(backupDir)
+
+	placed := make([]placedOutput, 0, len(outputPaths))
+	rollback := func() {
+		for i := len(placed) - 1; i >= 0; i-- {
+			os.Remove(placed[i].dest)
+			if placed[i].backup != "" {
+				os.Rename(placed[i].backup, placed[i].dest)
+			}
+		}
+	}
+
+	for _, outputPath := range outputPaths {
+		stagePath := filepath.Join(stageDir, filepath.FromSlash(outputPath))
+		output := placedOutput{dest: filepath.Join(destDir, filepath.FromSlash(outputPath))}
+		if err := os.MkdirAll(filepath.Dir(output.dest), 0755); err != nil {
+			rollback()
+			return nil, msgError("extract.mkdir_failed", err)
+		}
+		if FileExists(output.dest) {
+			output.backup = filepath.Join(backupDir, filepath.FromSlash(outputPath))
+			err := os.MkdirAll(filepath.Dir(output.backup), 0755)
+			if err == nil {
+				err = os.Rename(output.dest, output.backup)
+			}
+			if err != nil {
+				rollback()
+				return nil, msgError("replace.backup_failed", err)
+			}
+		}
+		if err := os.Rename(stagePath, output.dest); err != nil {
+			if output.backup != "" {
+				os.Rename(output.backup, output.dest)
+			}
+			rollback()
+			return nil, msgError("replace.rename_failed", err)
+		}
+		placed = append(placed, output)
+	}
+
+	outputs := make([]string, 0, len(placed))
+	for _, output := range placed {
+		outputs = append(outputs, output.dest)
+		if output.backup == "" || !keepOld {
+			continue
+		}
+		oldPath := output.dest + ".old"
+		os.Remove(oldPath)
+		if err := os.Rename(output.backup, oldPath); err != nil {
+			log.Warnf("extract.keep_old_failed", oldPath, err)
+			continue
+		}
+		log.Infof("replace.backed_up", oldPath)
+	}
+	return outputs, nil
+}
+
+// extractStream 解压tar或单文件压缩流
+func (e *archiveExtractor) extractStream(archivePath, archiveType string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	streamType, isTar := archiveType, true
+	switch archiveType {
+	case ArchiveTarGz:
+		streamType = ArchiveGz
+	case ArchiveTarXz:
+		streamType = ArchiveXz
+	case ArchiveTarBz:
+		streamType = ArchiveBz2
+	case ArchiveGz, ArchiveXz, ArchiveBz2:
+		isTar = false
+	}
+
+	reader, err := decompressReader(file, streamType)
+	if err != nil {
+		return msgError("extract.read_archive_failed", err)
+	}
+	if isTar {
+		return e.extractTar(reader)
+	}
+	return e.extractMember(singleStreamName(e.archiveName, reader), reader)
+}
+
+// singleStreamName 获取单文件压缩包的输出文件名
+// 优先去掉压缩后缀，其次使用gzip头中记录的原始文件名，否则在文件名后追加 .out
+func singleStreamName(name string, reader io.Reader) string {
+	lowerName := strings.ToLower(name)
+	for _, suffix := range []string{".gz", ".xz", ".bz2"} {
+		if strings.HasSuffix(lowerName, suffix) {
+			return name[:len(name)-len(suffix)]
+		}
+	}
+	if gzipReader, ok := reader.(*gzip.Reader); ok && gzipReader.Name != "" {
+		return path.Base(strings.ReplaceAll(gzipReader.Name, "\\", "/"))
+	}
+	return name + ".out"
+}
+
+// needsExtract 检查是否需要重新解压（从未解压、解压出的文件缺失或压缩包比解压记录新）
+func needsExtract(archivePath string) bool {
+	markerPath := archivePath + ExtractMarkerSuffix
+	markerInfo, err := os.Stat(markerPath)
+	if err != nil {
+		return true
+	}
+	archiveInfo, err := os.Stat(archivePath)
+	if err != nil || archiveInfo.ModTime().After(markerInfo.ModTime()) {
+		return true
+	}
+
+	data, err := os.ReadFile(markerPath)
+	if err != nil {
+		return true
+	}
+	var marker extractMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return true
+	}
+	for _, file := range marker.Files {
+		if !FileExists(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveExtractMarker 保存解压记录
+func saveExtractMarker(archivePath string, outputs []string) error {
+	data, err := json.MarshalIndent(extractMarker{Files: outputs, ExtractedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(archivePath+ExtractMarkerSuffix, data, 0644)
+}
+
+// postProcessItem 下载项的后处理（解压），force 为 false 时只在需要时解压
+func postProcessItem(item DownItem, storePath, downloadDir string, keepOld, force bool, log MsgLogger) error {
+	if item.Extract == nil {
+		return nil
+	}
+	if !force && !needsExtract(storePath) {
+		return nil
+	}
+	return extractItem(item.Extract, storePath, storePath, downloadDir, keepOld, log)
+}
+
+// extractItem 解压 archivePath 并在 storePath 旁记录解压结果
+// 下载完成时 archivePath 为尚未替换 storePath 的临时文件，解压失败时保存的压缩包保持不变
+func extractItem(config *ExtractConfig, archivePath, storePath, downloadDir string, keepOld bool, log MsgLogger) error {
+	destDir := extractDestDir(config, storePath, downloadDir)
+	outputs, err := extractArchive(config, archivePath, storePath, destDir, keepOld, log)
+	if err != nil {
+		return DownloadError{
+			Message: Msg("extract.failed", filepath.Base(storePath), err),
+			Type:    ErrExtractFailed,
+		}
+	}
+	for _, output := range outputs {
+		log.Infof("extract.extracted", output)
+	}
+	if err := saveExtractMarker(storePath, outputs); err != nil {
+		log.Warnf("extract.save_marker_failed", err)
+	}
+	return nil
+}
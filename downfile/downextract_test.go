@@ -0,0 +1,315 @@
+package downfile
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveMember 测试压缩包中的成员
+type archiveMember struct {
+	name string
+	data string
+}
+
+// writeTarGz 在 dir 中创建 tar.gz 压缩包
+func writeTarGz(t *testing.T, dir, name string, members []archiveMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, member := range members {
+		header := &tar.Header{Name: member.name, Mode: 0644, Size: int64(len(member.data)), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tarWriter.Write([]byte(member.data))
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	archivePath := filepath.Join(dir, name)
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+// writeZip 在 dir 中创建 zip 压缩包
+func writeZip(t *testing.T, dir, name string, members []archiveMember) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, member := range members {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: member.name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(member.data))
+	}
+	zipWriter.Close()
+	archivePath := filepath.Join(dir, name)
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+// assertNoTempDirs 确认解压临时目录已删除
+func assertNoTempDirs(t *testing.T, dir string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, ".extract-*"))
+	if len(matches) > 0 {
+		t.Errorf("temporary directories left behind: %v", matches)
+	}
+}
+
+func TestSafeMemberPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "data/a.mmdb", want: "data/a.mmdb"},
+		{name: "./data/../a.mmdb", want: "a.mmdb"},
+		{name: "../a.mmdb", wantErr: true},
+		{name: "data/../../a.mmdb", wantErr: true},
+		{name: "..\\a.mmdb", wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := safeMemberPath(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("safeMemberPath(%q) = %q, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("safeMemberPath(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExtractArchiveRejectsTraversal(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive func(t *testing.T, dir string) string
+	}{
+		{
+			name: "tar上级目录",
+			archive: func(t *testing.T, dir string) string {
+				return writeTarGz(t, dir, "evil.tar.gz", []archiveMember{{"ok.txt", "ok"}, {"../evil.txt", "evil"}})
+			},
+		},
+		{
+			name: "tar绝对路径",
+			archive: func(t *testing.T, dir string) string {
+				return writeTarGz(t, dir, "evil.tar.gz", []archiveMember{{"/tmp/evil.txt", "evil"}})
+			},
+		},
+		{
+			name: "zip上级目录",
+			archive: func(t *testing.T, dir string) string {
+				return writeZip(t, dir, "evil.zip", []archiveMember{{"ok.txt", "ok"}, {"sub/../../evil.txt", "evil"}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			destDir := filepath.Join(root, "dest")
+			archivePath := tt.archive(t, root)
+
+			outputs, err := extractArchive(&ExtractConfig{}, archivePath, archivePath, destDir, false, MsgLogger{Out: discardLogger{}})
+			if err == nil {
+				t.Fatalf("extractArchive() = %v, want error", outputs)
+			}
+			if FileExists(filepath.Join(root, "evil.txt")) {
+				t.Error("member written outside destination")
+			}
+			if FileExists(filepath.Join(destDir, "ok.txt")) {
+				t.Error("partial output written despite failure")
+			}
+			assertNoTempDirs(t, destDir)
+		})
+	}
+}
+
+func TestExtractArchiveFilesAndKeepOld(t *testing.T) {
+	root := t.TempDir()
+	archivePath := writeTarGz(t, root, "db.tar.gz", []archiveMember{
+		{"db_2024/GeoLite2-City.mmdb", "new city"},
+		{"db_2024/LICENSE.txt", "license"},
+	})
+	if err := os.WriteFile(filepath.Join(root, "GeoLite2-City.mmdb"), []byte("old city"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ExtractConfig{Files: []string{"*.mmdb"}, Flatten: true}
+	outputs, err := extractArchive(config, archivePath, archivePath, root, true, MsgLogger{Out: discardLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 1 || filepath.Base(outputs[0]) != "GeoLite2-City.mmdb" {
+		t.Fatalf("outputs = %v", outputs)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "GeoLite2-City.mmdb")); string(data) != "new city" {
+		t.Errorf("output = %q, want new content", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "GeoLite2-City.mmdb.old")); string(data) != "old city" {
+		t.Errorf("backup = %q, want old content", data)
+	}
+	if FileExists(filepath.Join(root, "LICENSE.txt")) {
+		t.Error("unmatched member extracted")
+	}
+	assertNoTempDirs(t, root)
+}
+
+func TestExtractArchiveRollsBackOnReplaceFailure(t *testing.T) {
+	root := t.TempDir()
+	archivePath := writeZip(t, root, "data.zip", []archiveMember{{"a.txt", "new a"}, {"sub/b.txt", "new b"}})
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("old a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// sub 是普通文件，无法创建 sub/b.txt，替换 a.txt 之后失败
+	if err := os.WriteFile(filepath.Join(root, "sub"), []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := extractArchive(&ExtractConfig{}, archivePath, archivePath, root, false, MsgLogger{Out: discardLogger{}}); err == nil {
+		t.Fatal("extractArchive() succeeded, want error")
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "old a" {
+		t.Errorf("a.txt = %q, want the old content restored", data)
+	}
+	assertNoTempDirs(t, root)
+}
+
+func TestExtractArchiveSizeLimit(t *testing.T) {
+	old := MaxExtractSize
+	MaxExtractSize = 1024
+	defer func() { MaxExtractSize = old }()
+
+	root := t.TempDir()
+	archivePath := writeTarGz(t, root, "bomb.tar.gz", []archiveMember{
+		{"a.bin", strings.Repeat("0", 600)},
+		{"b.bin", strings.Repeat("0", 600)},
+	})
+	if _, err := extractArchive(&ExtractConfig{}, archivePath, archivePath, root, false, MsgLogger{Out: discardLogger{}}); err == nil {
+		t.Fatal("extractArchive() succeeded, want size limit error")
+	}
+	if FileExists(filepath.Join(root, "a.bin")) {
+		t.Error("output written despite size limit")
+	}
+
+	// 单文件压缩流同样受限制
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	gzipWriter.Write(bytes.Repeat([]byte("0"), 4096))
+	gzipWriter.Close()
+	gzPath := filepath.Join(root, "bomb.txt.gz")
+	os.WriteFile(gzPath, buf.Bytes(), 0644)
+	if _, err := extractArchive(&ExtractConfig{}, gzPath, gzPath, root, false, MsgLogger{Out: discardLogger{}}); err == nil {
+		t.Fatal("extractArchive() succeeded for gz, want size limit error")
+	}
+	if FileExists(filepath.Join(root, "bomb.txt")) {
+		t.Error("gz output written despite size limit")
+	}
+}
+
+func TestExtractArchiveRejectsOverwritingArchive(t *testing.T) {
+	root := t.TempDir()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	// gzip头中记录的原始文件名与压缩包同名
+	gzipWriter.Name = "data"
+	gzipWriter.Write([]byte("content"))
+	gzipWriter.Close()
+	archivePath := filepath.Join(root, "data")
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := extractArchive(&ExtractConfig{}, archivePath, archivePath, root, false, MsgLogger{Out: discardLogger{}}); err == nil {
+		t.Fatal("extractArchive() overwrote the archive, want error")
+	}
+	if data, _ := os.ReadFile(archivePath); !bytes.Equal(data, buf.Bytes()) {
+		t.Error("archive modified")
+	}
+	assertNoTempDirs(t, root)
+
+	// 解压到其他目录时可以使用同名的输出文件
+	destDir := filepath.Join(root, "out")
+	if _, err := extractArchive(&ExtractConfig{}, archivePath, archivePath, destDir, false, MsgLogger{Out: discardLogger{}}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(destDir, "data")); string(data) != "content" {
+		t.Errorf("output = %q, want content", data)
+	}
+}
+
+func TestProcessItemExtractBeforeReplace(t *testing.T) {
+	valid := t.TempDir()
+	validArchive, err := os.ReadFile(writeTarGz(t, valid, "db.tar.gz", []archiveMember{{"db.mmdb", "new db"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content []byte
+		status  string
+		archive string // 处理后保存的压缩包内容
+		output  string // 处理后解压出的文件内容
+	}{
+		{name: "解压成功后替换压缩包", content: validArchive, status: StatusDownloaded, archive: string(validArchive), output: "new db"},
+		{name: "解压失败时保留原压缩包", content: []byte("not an archive"), status: StatusFailed, archive: "old archive", output: "old db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tt.content)
+			}))
+			defer server.Close()
+
+			d := testDownloader(t, WithRetries(1))
+			storePath := filepath.Join(d.outputDir, "db.tar.gz")
+			outputPath := filepath.Join(d.outputDir, "db.mmdb")
+			os.WriteFile(storePath, []byte("old archive"), 0644)
+			os.WriteFile(outputPath, []byte("old db"), 0644)
+
+			item := DownItem{
+				Module:       "db",
+				FileName:     "db.tar.gz",
+				DownloadURLs: []string{server.URL + "/db.tar.gz"},
+				KeepUpdated:  true,
+				Extract:      &ExtractConfig{Type: ArchiveTarGz},
+			}
+			result := d.DownloadItem(context.Background(), item)
+			if result.Status != tt.status {
+				t.Errorf("status = %s, want %s (%s)", result.Status, tt.status, result.Error)
+			}
+			if data, _ := os.ReadFile(storePath); string(data) != tt.archive {
+				t.Errorf("archive = %q, want %q", data, tt.archive)
+			}
+			if data, _ := os.ReadFile(outputPath); string(data) != tt.output {
+				t.Errorf("output = %q, want %q", data, tt.output)
+			}
+			if FileExists(storePath + PartialSuffix) {
+				t.Error("partial download left behind")
+			}
+			if tt.status == StatusDownloaded && needsExtract(storePath) {
+				t.Error("extract marker missing or older than the archive")
+			}
+			assertNoTempDirs(t, d.outputDir)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package downfile
+
+import (
+	"path"
+	"strings"
+)
+
+// TaskFilter 下载任务选择条件，所有模式都支持通配符（如 geoip-*）
+// 同一条件的多个模式满足其一即可，不同条件需要同时满足，条件为空时不限制
+type TaskFilter struct {
+	Groups   []string // 配置组名称
+	Modules  []string // 模块名
+	Tags     []string // 标签，下载项包含任一匹配的标签即可
+	Excludes []string // 排除模块名或配置组名称匹配的下载项
+}
+
+// IsEmpty 判断是否没有设置任何选择条件
+func (f TaskFilter) IsEmpty() bool {
+	return len(f.Groups) == 0 && len(f.Modules) == 0 && len(f.Tags) == 0 && len(f.Excludes) == 0
+}
+
+// Validate 检查所有模式是否是有效的通配符模式
+func (f TaskFilter) Validate() error {
+	for _, patterns := range [][]string{f.Groups, f.Modules, f.Tags, f.Excludes} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return msgError("filter.invalid_pattern", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// Match 判断下载任务是否满足选择条件
+func (f TaskFilter) Match(task DownTask) bool {
+	if len(f.Groups) > 0 && !matchAny(f.Groups, task.Group) {
+		return false
+	}
+	if len(f.Modules) > 0 && !matchAny(f.Modules, task.Item.Module) {
+		return false
+	}
+	if len(f.Tags) > 0 && !matchAnyTag(f.Tags, task.Item.Tags) {
+		return false
+	}
+	return !matchAny(f.Excludes, task.Item.Module) && !matchAny(f.Excludes, task.Group)
+}
+
+// String 获取选择条件的文本描述
+func (f TaskFilter) String() string {
+	var parts []string
+	for _, condition := range []struct {
+		name     string
+		patterns []string
+	}{{"group", f.Groups}, {"module", f.Modules}, {"tag", f.Tags}, {"exclude", f.Excludes}} {
+		if len(condition.patterns) > 0 {
+			parts = append(parts, condition.name+"="+strings.Join(condition.patterns, ","))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// FilterDownTasks 获取满足选择条件的下载任务，保持原有顺序
+func FilterDownTasks(tasks []DownTask, filter TaskFilter) []DownTask {
+	if filter.IsEmpty() {
+		return tasks
+	}
+	var selected []DownTask
+	for _, task := range tasks {
+		if filter.Match(task) {
+			selected = append(selected, task)
+		}
+	}
+	return selected
+}
+
+// matchAny 判断名称是否匹配任一模式
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyTag 判断是否有任一标签匹配任一模式
+func matchAnyTag(patterns []string, tags []string) bool {
+	for _, tag := range tags {
+		if matchAny(patterns, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package downfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+// filterTasks 测试用的下载任务
+var filterTasks = []DownTask{
+	{Group: "geoip", Item: DownItem{Module: "geoip-city", Tags: []string{"geo", "daily"}}},
+	{Group: "geoip", Item: DownItem{Module: "geoip-asn", Tags: []string{"geo"}}},
+	{Group: "ipdb", Item: DownItem{Module: "qqwry", Tags: []string{"cn"}}},
+	{Group: "rules", Item: DownItem{Module: "geoip-rules"}},
+}
+
+func TestFilterDownTasks(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter TaskFilter
+		want   []string // 选中的模块名，保持原有顺序
+	}{
+		{name: "无条件", filter: TaskFilter{}, want: []string{"geoip-city", "geoip-asn", "qqwry", "geoip-rules"}},
+		{name: "配置组通配符", filter: TaskFilter{Groups: []string{"geo*"}}, want: []string{"geoip-city", "geoip-asn"}},
+		{name: "模块通配符", filter: TaskFilter{Modules: []string{"geoip-*"}}, want: []string{"geoip-city", "geoip-asn", "geoip-rules"}},
+		{name: "标签通配符", filter: TaskFilter{Tags: []string{"da*"}}, want: []string{"geoip-city"}},
+		{name: "同一条件满足其一", filter: TaskFilter{Modules: []string{"qqwry", "geoip-asn"}}, want: []string{"geoip-asn", "qqwry"}},
+		{name: "同一条件的多个标签", filter: TaskFilter{Tags: []string{"cn", "daily"}}, want: []string{"geoip-city", "qqwry"}},
+		{name: "不同条件同时满足", filter: TaskFilter{Groups: []string{"geoip", "rules"}, Modules: []string{"geoip-*"}, Tags: []string{"geo"}}, want: []string{"geoip-city", "geoip-asn"}},
+		{name: "没有匹配", filter: TaskFilter{Groups: []string{"ipdb"}, Tags: []string{"geo"}}, want: nil},
+		{name: "按模块名排除", filter: TaskFilter{Excludes: []string{"geoip-c*"}}, want: []string{"geoip-asn", "qqwry", "geoip-rules"}},
+		{name: "按配置组排除", filter: TaskFilter{Excludes: []string{"geoip"}}, want: []string{"qqwry", "geoip-rules"}},
+		{name: "排除优先于选择", filter: TaskFilter{Modules: []string{"geoip-*"}, Excludes: []string{"rules"}}, want: []string{"geoip-city", "geoip-asn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, task := range FilterDownTasks(filterTasks, tt.filter) {
+				got = append(got, task.Item.Module)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterDownTasks(%s) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskFilterMatchWithoutTags(t *testing.T) {
+	// 设置了标签条件时，没有标签的下载项不匹配
+	task := DownTask{Group: "rules", Item: DownItem{Module: "geoip-rules"}}
+	if (TaskFilter{Tags: []string{"*"}}).Match(task) {
+		t.Error("Match() selected an item without tags")
+	}
+	if !(TaskFilter{Groups: []string{"*"}}).Match(task) {
+		t.Error("Match() rejected an item matching every group")
+	}
+}
+
+func TestTaskFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  TaskFilter
+		wantErr bool
+	}{
+		{name: "有效模式", filter: TaskFilter{Groups: []string{"geo*"}, Modules: []string{"geoip-?ity"}, Tags: []string{"[a-c]*"}, Excludes: []string{"x"}}},
+		{name: "无效的配置组模式", filter: TaskFilter{Groups: []string{"geo["}}, wantErr: true},
+		{name: "无效的模块模式", filter: TaskFilter{Modules: []string{"[a-"}}, wantErr: true},
+		{name: "无效的标签模式", filter: TaskFilter{Tags: []string{"\\"}}, wantErr: true},
+		{name: "无效的排除模式", filter: TaskFilter{Excludes: []string{"ok", "bad["}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package downfile
+
+import "os"
+
+// lockFile 当前平台不支持文件锁，只保证进程内的互斥
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
+
+// tryLockFile 当前平台不支持文件锁，总是返回打开的锁文件
+func tryLockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+}
+
+// unlockFile 关闭锁文件
+func unlockFile(file *os.File) {
+	file.Close()
+}
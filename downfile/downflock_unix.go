@@ -0,0 +1,47 @@
+//go:build unix
+
+package downfile
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile 获取文件的排他锁（阻塞等待其他进程释放），返回释放锁的函数，锁文件不存在时创建
+func lockFile(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(file)
+	}, nil
+}
+
+// tryLockFile 尝试获取文件的排他锁，不等待，其他进程持有锁时返回nil，锁文件不存在时创建
+func tryLockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// unlockFile 释放文件锁并关闭文件
+func unlockFile(file *os.File) {
+	unix.Flock(int(file.Fd()), unix.LOCK_UN)
+	file.Close()
+}
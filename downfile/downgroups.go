@@ -1,98 +1,278 @@
 package downfile
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
 // ProcessDownItems 处理配置组
 func ProcessDownItems(client *http.Client, items []DownItem, downloadDir string, forceUpdate bool, keepOld bool, retries int) int {
-	successCount := 0
+	tasks := make([]DownTask, 0, len(items))
 	for _, item := range items {
-		// 组合最终文件路径 // 不是绝对路径，才拼接下载目录
-		storePath := GetItemFilePath(item.FileName, downloadDir)
+		tasks = append(tasks, DownTask{Item: item})
+	}
+	return CountSuccess(ProcessDownTasks(context.Background(), client, tasks, downloadDir, forceUpdate, keepOld, retries, 1))
+}
 
-		// 检查文件是否存在以及是否需要更新
-		fileExists := FileExists(storePath)
-		needsUpdate := forceUpdate || !fileExists || (item.KeepUpdated && NeedsUpdate(storePath))
+// processItem 处理单个下载项，返回处理结果
+// ctx 结束（如超过整体运行时间限制）或超过下载项的时间限制时不再尝试其他下载源
+func (d *Downloader) processItem(ctx context.Context, item DownItem, manifests *checksumManifests, log MsgLogger) (result DownResult) {
 
-		if fileExists && !needsUpdate {
-			fmt.Printf("  文件 %s 已存在且不需要更新，跳过下载\n", item.FileName)
-			successCount++
-			continue
-		}
+	result = DownResult{Module: item.Module, FileName: item.FileName, Status: StatusFailed}
+	startTime := time.Now()
+	defer func() {
+		result.Duration = time.Since(startTime)
+	}()
+
+	if ctx.Err() != nil {
+		log.Warnf("item.run_timeout_skip", item.Module)
+		result.Error = Msg("item.run_timeout")
+		return result
+	}
 
-		//创建目录并存储结果
-		err := MakeDirs(storePath, true)
+	// 下载项的时间限制从此开始计算，包括解析 Release、获取校验清单、竞速探测及所有下载源的重试
+	runCtx := ctx
+	ctx, cancel := itemContext(ctx, item)
+	defer cancel()
+
+	// 使用锁定文件时只下载锁定记录中的文件
+	if d.lock != nil {
+		locked, err := d.lockedItem(item)
 		if err != nil {
-			fmt.Printf("  目录[%s]初始化失败:%v\n", item.FileName, err)
-			continue
+			log.Errorf("item.error", err)
+			result.Error = err.Error()
+			return result
+		}
+		item = locked
+	}
+
+	// 组合最终文件路径 // 不是绝对路径，才拼接下载目录
+	storePath := GetItemFilePath(item.FileName, d.outputDir)
+
+	// 检查文件是否存在以及是否需要更新
+	fileExists := FileExists(storePath)
+	needsUpdate := d.needsUpdate(item, storePath, fileExists)
+
+	if fileExists && !needsUpdate {
+		log.Infof("item.skip_existing", item.FileName)
+		// 解压出的文件缺失时重新解压
+		if err := postProcessItem(item, storePath, d.outputDir, d.keepOld, false, log); err != nil {
+			log.Errorf("item.error", err)
+			result.Error = err.Error()
+			return result
+		}
+		result.Status = StatusSkipped
+		return result
+	}
+
+	//创建目录并存储结果
+	err := MakeDirs(storePath, true)
+	if err != nil {
+		log.Errorf("item.mkdir_failed", item.FileName, err)
+		result.Error = err.Error()
+		return result
+	}
+	log.Infof("item.start", item.Module)
+
+	// 解析 GitHub Release 的下载地址，Release 未变化时不重新下载
+	item, release, err := d.resolveItemRelease(ctx, item, log)
+	if err != nil {
+		log.Errorf("item.error", err)
+		result.Error = err.Error()
+		return result
+	}
+	if fileExists && d.releaseUnchanged(storePath, release) {
+		log.Infof("release.unchanged", item.FileName, release.Tag)
+		if err := d.cache.UpdateDownloadTime(storePath); err != nil {
+			log.Errorf("cache.update_failed", err)
 		}
-		fmt.Printf("  开始下载 %s...\n", item.Module)
-
-		success := false
-		resourceNotFound := false
-
-		// 尝试从每个URL下载
-		for _, url := range item.DownloadURLs {
-			// 处理GitHub URL
-			downloadURL := url
-			if strings.Contains(url, "github.com") && strings.Contains(url, "/blob/") {
-				downloadURL = ConvertGitHubURL(url)
-				fmt.Printf("    转换GitHub URL: %s -> %s\n", url, downloadURL)
+		if err := postProcessItem(item, storePath, d.outputDir, d.keepOld, false, log); err != nil {
+			log.Errorf("item.error", err)
+			result.Error = err.Error()
+			return result
+		}
+		result.Status = StatusNotModified
+		return result
+	}
+
+	// 获取期望的校验值（包括远程校验清单）
+	checksums, err := resolveItemChecksums(ctx, d.client, item, manifests)
+	if err != nil {
+		log.Errorf("item.checksum_unavailable", err, item.Module)
+		result.Error = err.Error()
+		return result
+	}
+
+	success := false
+	triedMirrors, notFoundMirrors := 0, 0
+	var lastErr error
+
+	// 按下载源排序策略确定尝试顺序，启用竞速时最先响应的下载源优先
+	downloadURLs := d.itemMirrors(item, log)
+	if item.Race && len(downloadURLs) > 1 {
+		downloadURLs = raceMirrors(ctx, d.client, downloadURLs, log)
+	}
+
+	// 尝试从每个URL下载
+	for _, downloadURL := range downloadURLs {
+		triedMirrors++
+		// 尝试下载，支持重试
+		for attempt := 1; attempt <= d.retries; attempt++ {
+			if attempt > 1 {
+				log.Infof("item.retry", attempt)
+			} else {
+				log.Infof("item.try_url", downloadURL)
 			}
 
-			// 尝试下载，支持重试
-			for attempt := 1; attempt <= retries; attempt++ {
-				if attempt > 1 {
-					fmt.Printf("    第 %d 次重试下载...\n", attempt)
-				} else {
-					fmt.Printf("    尝试从 %s 下载...\n", downloadURL)
+			// 使用普通的HTTP请求
+			request := downloadRequest{
+				URL:         downloadURL,
+				StorePath:   storePath,
+				KeepOld:     d.keepOld,
+				Checksums:   checksums,
+				Conditional: !d.forceUpdate && d.lock == nil,
+				SpeedPolicy: d.speedPolicy.ForItem(item),
+				Logger:      log,
+				Cache:       d.cache,
+				Reporter:    d.reporter,
+				Segments:    d.itemSegments(item),
+			}
+			if downloadURL == release.URL {
+				request.Tag = release.Tag
+			}
+			if item.SegmentMirrors {
+				request.Mirrors = otherMirrors(downloadURLs, downloadURL)
+			}
+			if item.Extract != nil {
+				// 在替换保存的压缩包前解压，解压失败时不保留新下载的压缩包
+				request.BeforeReplace = func(tempFile string) error {
+					return extractItem(item.Extract, tempFile, storePath, d.outputDir, d.keepOld, log)
 				}
+			}
+			result.URL = downloadURL
+			result.Attempts++
+			transferred, err := downloadFile(ctx, d.client, request)
+			result.Bytes += transferred
+			if err != nil {
+				var downloadErr DownloadError
+				if errors.As(err, &downloadErr) && downloadErr.Type == ErrNotModified {
+					log.Infof("item.not_modified", item.FileName)
+					success = true
+					result.Status = StatusNotModified
+					break
+				}
+				lastErr = err
 
-				// 使用普通的HTTP请求
-				if err := downloadFile(client, downloadURL, storePath, keepOld); err != nil {
-					// 检查是否是404错误
-					var downloadErr DownloadError
-					fmt.Printf("    下载失败: %v\n", err)
+				// 检查是否是404错误
+				log.Warnf("item.download_failed", err)
 
-					if errors.As(err, &downloadErr) && downloadErr.Type == ErrResourceNotFound {
-						fmt.Printf("    资源不存在 (404)，请检查配置中的URL是否正确\n")
-						resourceNotFound = true
-						break // 404错误不需要重试
-					}
+				if runCtx.Err() != nil {
+					log.Warnf("item.run_timeout_stop")
+					result.Error = err.Error()
+					return result
+				}
+
+				if ctx.Err() != nil {
+					log.Warnf("item.timeout_stop", item.Timeout)
+					result.Error = err.Error()
+					return result
+				}
+
+				if errors.As(err, &downloadErr) && downloadErr.Type == ErrTimeout {
+					log.Warnf("item.timeout_next")
+					break // 还有剩余时间时超时换下一个下载源
+				}
+
+				if errors.As(err, &downloadErr) && downloadErr.Type == ErrResourceNotFound {
+					log.Warnf("item.not_found")
+					notFoundMirrors++
+					break // 404错误不需要重试
+				}
+
+				if errors.As(err, &downloadErr) && downloadErr.Type == ErrChecksumMismatch {
+					log.Warnf("item.checksum_next")
+					break // 校验失败重试同一下载源无意义
+				}
+
+				if errors.As(err, &downloadErr) && downloadErr.Type == ErrExtractFailed {
+					log.Warnf("item.extract_next")
+					break // 压缩包无法解压，重试同一下载源无意义
+				}
 
-					// 如果不是最后一次尝试，则等待后重试
-					if attempt < retries {
-						waitTime := time.Duration(attempt) * 2 * time.Second
-						fmt.Printf("    等待 %v 后重试...\n", waitTime)
-						time.Sleep(waitTime)
-						continue
+				// 如果不是最后一次尝试，则等待后重试
+				if attempt < d.retries {
+					waitTime := time.Duration(attempt) * 2 * time.Second
+					log.Infof("item.retry_wait", waitTime)
+					select {
+					case <-time.After(waitTime):
+					case <-ctx.Done():
 					}
-					break // 所有重试都失败
-				} else {
-					fmt.Printf("    成功下载 %s 到 %s\n", item.Module, storePath)
-					successCount++
-					success = true
-					break // 下载成功，不需要继续重试
+					continue
 				}
+				break // 所有重试都失败
+			} else {
+				log.Infof("item.success", item.Module, storePath)
+				success = true
+				result.Status = StatusDownloaded
+				break // 下载成功，不需要继续重试
 			}
+		}
 
-			if success || resourceNotFound {
-				break // 当前URL下载成功或资源不存在，不需要尝试下一个URL
-			}
+		if success {
+			break // 当前URL下载成功，不需要尝试下一个URL
+		}
+	}
+
+	// 排序策略、竞速及改写规则可能把过期的下载源排在前面，所有下载源都返回404才判定资源不存在
+	resourceNotFound := triedMirrors > 0 && notFoundMirrors == triedMirrors
+
+	// 文件未修改时解压出的文件缺失则重新解压，重新下载的文件在替换前已解压
+	if success {
+		if err := postProcessItem(item, storePath, d.outputDir, d.keepOld, false, log); err != nil {
+			log.Errorf("item.error", err)
+			result.Status = StatusFailed
+			result.Error = err.Error()
 		}
+		return result
+	}
 
-		if !success {
-			if resourceNotFound {
-				fmt.Printf("  警告: %s 的资源不存在，请检查配置文件中的URL\n", item.Module)
-			} else {
-				fmt.Printf("  错误: 所有下载源都失败，无法下载 %s\n", item.Module)
-			}
+	if resourceNotFound {
+		log.Warnf("item.resource_missing", item.Module)
+		result.Status = StatusNotFound
+	} else {
+		log.Errorf("item.all_failed", item.Module)
+	}
+	if lastErr != nil {
+		result.Error = lastErr.Error()
+	}
+	return result
+}
+
+// itemSegments 获取下载项分段下载的并行连接数，下载项未配置时使用下载器设置
+func (d *Downloader) itemSegments(item DownItem) int {
+	if item.Segments > 0 {
+		return item.Segments
+	}
+	return d.segments
+}
+
+// otherMirrors 获取除 current 之外的下载地址
+func otherMirrors(urls []string, current string) []string {
+	var mirrors []string
+	for _, url := range urls {
+		if url != current {
+			mirrors = append(mirrors, url)
 		}
 	}
-	return successCount
+	return mirrors
+}
+
+// itemContext 为下载项创建上下文，下载项配置了 timeout 时限制整个下载项的处理时间
+func itemContext(ctx context.Context, item DownItem) (context.Context, context.CancelFunc) {
+	if item.Timeout > 0 {
+		return context.WithTimeout(ctx, item.Timeout)
+	}
+	return context.WithCancel(ctx)
 }
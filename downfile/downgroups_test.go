@@ -0,0 +1,193 @@
+package downfile
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDownloader 创建不输出日志及进度、使用临时状态目录的下载器
+func testDownloader(t *testing.T, opts ...Option) *Downloader {
+	t.Helper()
+	outputDir := t.TempDir()
+	base := []Option{
+		WithOutputDir(outputDir),
+		WithCache(NewStateStore(DefaultStateDir(outputDir), outputDir, 0)),
+		WithLogger(discardLogger{}),
+		WithProgressReporter(SilentProgressReporter),
+	}
+	d, err := NewDownloader(append(base, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// stallServer 返回响应头及少量数据后不再发送数据，直到请求被取消
+func stallServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProcessItemTimeoutCoversAllAttempts(t *testing.T) {
+	servers := []*httptest.Server{stallServer(t), stallServer(t), stallServer(t)}
+	item := DownItem{
+		Module:   "stall",
+		FileName: "stall.bin",
+		Timeout:  300 * time.Millisecond,
+		MinSpeed: -1,
+	}
+	for _, server := range servers {
+		item.DownloadURLs = append(item.DownloadURLs, server.URL+"/stall.bin")
+	}
+
+	d := testDownloader(t, WithRetries(3))
+	start := time.Now()
+	result := d.DownloadItem(context.Background(), item)
+	elapsed := time.Since(start)
+
+	if result.Status != StatusFailed {
+		t.Errorf("status = %s, want %s", result.Status, StatusFailed)
+	}
+	if result.Attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (deadline reached during the first attempt)", result.Attempts)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("item took %v, want about %v", elapsed, item.Timeout)
+	}
+}
+
+func TestProcessItemLowSpeedTriesNextMirror(t *testing.T) {
+	stalled := stallServer(t)
+	content := []byte("content")
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	t.Cleanup(healthy.Close)
+
+	// 第一个下载源停滞，低速检测取消传输后尝试下一个下载源
+	policy := SpeedPolicy{MinSpeed: 1024, CheckInterval: 100 * time.Millisecond}
+	d := testDownloader(t, WithRetries(1), WithSpeedPolicy(policy))
+	item := DownItem{
+		Module:       "stall",
+		FileName:     "stall.bin",
+		DownloadURLs: []string{stalled.URL + "/stall.bin", healthy.URL + "/stall.bin"},
+	}
+	start := time.Now()
+	result := d.DownloadItem(context.Background(), item)
+
+	if result.Status != StatusDownloaded || result.URL != healthy.URL+"/stall.bin" {
+		t.Errorf("result = %s from %s, want %s from the second mirror", result.Status, result.URL, StatusDownloaded)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", result.Attempts)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("item took %v, want the stalled mirror cancelled after about %v", elapsed, policy.CheckInterval)
+	}
+	if data, err := os.ReadFile(GetItemFilePath(item.FileName, d.outputDir)); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("file = %q (%v), want %q", data, err, content)
+	}
+}
+
+// statusServer 对 GET 请求返回 getStatus，对 HEAD 请求返回 headStatus
+func statusServer(t *testing.T, headStatus, getStatus int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(headStatus)
+			return
+		}
+		w.WriteHeader(getStatus)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProcessItemNotFoundOnlyWhenAllMirrorsMissing(t *testing.T) {
+	tests := []struct {
+		name         string
+		race         bool
+		statuses     [][2]int // 每个下载源的 HEAD、GET 响应状态
+		wantStatus   string
+		wantAttempts int
+	}{
+		{
+			name:         "唯一下载源404",
+			statuses:     [][2]int{{404, 404}},
+			wantStatus:   StatusNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:         "所有下载源404",
+			statuses:     [][2]int{{404, 404}, {404, 404}},
+			wantStatus:   StatusNotFound,
+			wantAttempts: 2,
+		},
+		{
+			name:         "排在前面的下载源404后继续尝试",
+			statuses:     [][2]int{{404, 404}, {200, 200}},
+			wantStatus:   StatusDownloaded,
+			wantAttempts: 2,
+		},
+		{
+			name:         "其他下载源失败后404",
+			statuses:     [][2]int{{500, 500}, {404, 404}},
+			wantStatus:   StatusFailed,
+			wantAttempts: 2,
+		},
+		{
+			name:         "竞速胜出者失败后探测404的下载源",
+			race:         true,
+			statuses:     [][2]int{{404, 404}, {200, 500}},
+			wantStatus:   StatusFailed,
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := DownItem{Module: "missing", FileName: "missing.bin", Race: tt.race}
+			for _, status := range tt.statuses {
+				item.DownloadURLs = append(item.DownloadURLs, statusServer(t, status[0], status[1]).URL+"/missing.bin")
+			}
+			result := testDownloader(t).DownloadItem(context.Background(), item)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", result.Status, tt.wantStatus)
+			}
+			if result.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", result.Attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestProcessItemStaleFastestMirror(t *testing.T) {
+	stale := statusServer(t, 404, 404)
+	good := statusServer(t, 200, 200)
+	d := testDownloader(t, WithMirrorStrategy(MirrorFastest))
+	// 历史统计中过期的下载源最快，排序后最先尝试
+	if err := d.cache.RecordHost(stale.URL+"/a.bin", true, time.Millisecond, 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	item := DownItem{Module: "a", FileName: "a.bin", DownloadURLs: []string{good.URL + "/a.bin", stale.URL + "/a.bin"}}
+	if urls := d.itemMirrors(item, d.log("")); urls[0] != stale.URL+"/a.bin" {
+		t.Fatalf("itemMirrors() = %v, want the stale mirror first", urls)
+	}
+
+	result := d.DownloadItem(context.Background(), item)
+	if result.Status != StatusDownloaded || result.URL != good.URL+"/a.bin" {
+		t.Errorf("result = %s from %s, want %s from the working mirror", result.Status, result.URL, StatusDownloaded)
+	}
+}
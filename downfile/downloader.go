@@ -1,6 +1,8 @@
 package downfile
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,68 +23,223 @@ func (e DownloadError) Error() string {
 	return e.Message
 }
 
-// downloadFile 下载文件
-func downloadFile(client *http.Client, downloadUrl, storePath string, keepOldFile bool) error {
+// downloadRequest 单次下载请求参数
+type downloadRequest struct {
+	URL         string            // 下载地址
+	StorePath   string            // 保存路径
+	KeepOld     bool              // 是否保留旧文件（重命名为.old）
+	Checksums   map[string]string // 期望的校验值，非空时在替换目标文件前校验内容
+	Conditional bool              // 是否根据缓存的ETag/Last-Modified发送条件请求
+	SpeedPolicy SpeedPolicy       // 低速检测策略
+	Logger      MsgLogger         // 日志输出
+	Cache       CacheStore        // 下载缓存，为nil时使用全局设置的默认缓存
+	Reporter    ProgressReporter  // 进度报告器，为nil时在终端绘制进度条
+	Segments    int               // 分段下载的连接数，小于等于1时不分段
+	Mirrors     []string          // 分段下载时可同时使用的备用下载源
+	Tag         string            // 下载的 GitHub Release 标签，下载成功后记录到缓存中
+	// BeforeReplace 校验通过后、替换目标文件前对下载完成的临时文件进行的处理（如解压），返回错误时不替换目标文件
+	BeforeReplace func(tempFile string) error
+}
+
+// cache 获取下载请求使用的下载缓存
+func (r downloadRequest) cache() CacheStore {
+	if r.Cache != nil {
+		return r.Cache
+	}
+	return nopCache{}
+}
+
+// downloadFile 下载文件，整个传输过程在可取消的 ctx 下进行，低速检测触发时中止传输
+// 返回本次实际传输的字节数（不包括续传前已下载的部分），下载失败时同样返回
+func downloadFile(ctx context.Context, client *http.Client, request downloadRequest) (transferred int64, err error) {
+	downloadUrl, storePath, keepOldFile := request.URL, request.StorePath, request.KeepOld
+	runCtx := ctx
+
+	// 创建可取消的上下文，供低速检测中止传输
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 创建内容校验器
+	verifier, err := newChecksumVerifier(request.Checksums)
+	if err != nil {
+		return 0, err
+	}
+
 	// 创建目标文件的目录（如果不存在）
 	if err := os.MkdirAll(filepath.Dir(storePath), 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %w", err)
+		return 0, msgError("download.mkdir_failed", err)
 	}
 
-	// 创建临时文件（使用唯一名称避免冲突）
-	tempFile := storePath + fmt.Sprintf(".%d.download", time.Now().UnixNano())
-	out, err := os.Create(tempFile)
+	// 使用固定的未完成下载文件，支持失败后断点续传
+	// 整个传输过程持有文件锁，多个进程下载同一文件时依次进行
+	unlock, err := lockPartial(ctx, storePath, request.Logger)
 	if err != nil {
-		return fmt.Errorf("创建临时文件失败: %w", err)
+		return 0, err
+	}
+	defer unlock()
+	tempFile, metaFile := partialPaths(storePath)
+	offset, validator := resumeOffset(storePath, downloadUrl)
+	if offset == 0 {
+		removePartial(storePath)
 	}
 
-	resp, err := httpGet(client, downloadUrl, err)
+	// 已有部分下载内容时发送范围请求，If-Range 保证服务器资源未变化
+	header := make(http.Header)
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		header.Set("If-Range", validator)
+	} else if request.Conditional {
+		// 本地文件与缓存记录一致时发送条件请求，服务器文件未变化时返回304
+		setConditionalHeaders(header, request.cache(), storePath, downloadUrl)
+	}
+
+	// 记录下载源的下载结果，用于下载源排序
+	startTime := time.Now()
+	var latency time.Duration
+	defer func() {
+		if !errors.Is(runCtx.Err(), context.Canceled) {
+			recordMirrorResult(request.cache(), downloadUrl, time.Since(startTime), latency, transferred, err, request.Logger)
+		}
+	}()
+
+	resp, err := httpGet(ctx, client, downloadUrl, header)
+	if err == nil || errors.As(err, new(DownloadError)) {
+		latency = time.Since(startTime)
+	}
 	if err != nil {
-		return err
+		if timeoutErr := deadlineError(ctx); timeoutErr != nil {
+			return 0, timeoutErr
+		}
+		var downloadErr DownloadError
+		if errors.As(err, &downloadErr) && downloadErr.Type == ErrNotModified {
+			// 文件未修改，只刷新缓存时间
+			if err := request.cache().UpdateDownloadTime(storePath); err != nil {
+				request.Logger.Errorf("cache.update_failed", err)
+			}
+			return 0, err
+		}
+		if offset > 0 && errors.As(err, &downloadErr) && downloadErr.Type == ErrRangeNotSatisfiable {
+			// 续传范围无效，删除未完成文件后从头下载
+			request.Logger.Infof("download.range_invalid_restart")
+			removePartial(storePath)
+			offset = 0
+			resp, err = httpGet(ctx, client, downloadUrl, nil)
+		}
+		if err != nil {
+			return 0, err
+		}
 	}
 	defer resp.Body.Close()
 
+	if offset > 0 {
+		if resp.StatusCode == http.StatusPartialContent {
+			request.Logger.Infof("download.resume_from", FormatSize(offset))
+		} else {
+			request.Logger.Infof("download.resume_unsupported")
+		}
+	}
+
+	// 服务器支持范围请求且文件足够大时分段下载
+	segments := segmentCount(request.Segments, resp, offset)
+
+	// 打开未完成下载文件，续传时将已下载部分计入校验值
+	out, offset, err := openPartialFile(tempFile, resp, offset, verifier.Writer())
+	if err != nil {
+		removePartial(storePath)
+		return 0, err
+	}
+
+	// 记录续传所需的元数据
+	totalSize := resp.ContentLength
+	if totalSize >= 0 {
+		totalSize += offset
+	}
+	meta := &partialMeta{
+		URL:          downloadUrl,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		TotalSize:    totalSize,
+	}
+	if segments <= 1 {
+		// 分段下载的文件中间可能有空洞，不记录续传元数据
+		if err := savePartialMeta(metaFile, meta); err != nil {
+			request.Logger.Warnf("download.save_partial_meta_failed", err)
+		}
+	}
+
 	// 获取文件大小
-	fileSize := resp.ContentLength
+	fileSize := totalSize
 	fileName := filepath.Base(storePath)
 
-	// 使用defer确保在函数退出时处理临时文件
-	var downloadSuccess bool
+	// 使用defer确保在函数退出时处理未完成下载文件
+	var downloadSuccess, keepPartial bool
 	defer func() {
 		out.Close()
-		if !downloadSuccess {
-			// 下载失败，删除临时文件
-			os.Remove(tempFile)
+		if !downloadSuccess && !keepPartial {
+			// 下载失败且无法续传，删除未完成下载文件
+			removePartial(storePath)
 		}
 	}()
 
 	// 创建进度跟踪器
 	tracker := NewProgressTracker(fileSize, fileName)
-	defer tracker.Close()
+	tracker.Logger = request.Logger
+	if request.Reporter != nil {
+		tracker.Reporter = request.Reporter
+	}
+	tracker.SetStartOffset(offset)
+	if segments > 1 {
+		tracker.SetSegments(segments)
+	}
+	tracker.Policy = request.SpeedPolicy
+	tracker.Cancel = cancel
+	defer func() {
+		tracker.Finish(err)
+	}()
+	defer func() {
+		transferred = tracker.BytesCount.Load() - tracker.StartOffset
+	}()
 
-	// 启动进度监控协程
+	// 报告开始下载并启动进度监控协程
+	tracker.Start()
 	go tracker.MonitorSpeed()
 	go tracker.DisplayProgress()
 
-	// 创建计数Writer
-	countingWriter := tracker.GetCountingWriter(out)
+	// 创建计数Writer，同步计算哈希
+	countingWriter := io.MultiWriter(tracker.GetCountingWriter(out), verifier.Writer())
 
 	// 复制内容，支持取消
-	buf := make([]byte, DownloadBufferSize)
-	_, err = copyBuffer(countingWriter, resp.Body, buf)
+	if segments > 1 {
+		err = downloadSegments(ctx, client, request, resp, out, tracker, segments, meta.validator())
+	} else {
+		buf := make([]byte, DownloadBufferSize)
+		_, err = copyBuffer(countingWriter, resp.Body, buf)
+	}
+
+	// 中断时保留已下载的部分，服务器提供了校验标识时下次可以续传
+	keepPartial = segments <= 1 && meta.validator() != "" && tracker.BytesCount.Load() > 0
 
 	// 检查是否是因为速度过低取消导致的错误
 	cancelReason := tracker.GetCancelReason()
 	if cancelReason == ErrLowSpeed {
-		return DownloadError{
-			Message: fmt.Sprintf("下载已取消: 速度过低，低于最小要求 (%s/s)，网络可能存在问题",
-				formatSize(int64(MinRequiredSpeed))),
+		return 0, DownloadError{
+			Message: Msg("download.low_speed_cancelled",
+				FormatSize(int64(request.SpeedPolicy.MinSpeed))),
 			Type: ErrLowSpeed,
 		}
 	}
 
+	// 检查是否超过下载时间限制
+	if timeoutErr := deadlineError(ctx); timeoutErr != nil {
+		return 0, timeoutErr
+	}
+
 	// 检查其他错误
 	if err != nil {
-		return fmt.Errorf("下载内容失败: %w", err)
+		if segments > 1 {
+			return 0, err
+		}
+		return 0, msgError("download.copy_failed", err)
 	}
 
 	// 显示下载摘要
@@ -90,76 +247,180 @@ func downloadFile(client *http.Client, downloadUrl, storePath string, keepOldFil
 
 	// 关闭文件，确保内容写入磁盘
 	if err := out.Close(); err != nil {
-		return fmt.Errorf("关闭文件失败: %w", err)
+		return 0, msgError("download.close_failed", err)
+	}
+
+	// 分段下载时各分段并行写入，完成后再计算哈希
+	if segments > 1 {
+		if err := hashFile(tempFile, verifier.Writer()); err != nil {
+			return 0, err
+		}
+	}
+
+	// 校验文件内容，不匹配时保留原文件不被替换
+	if verifier.Configured() {
+		if err := verifier.Verify(); err != nil {
+			keepPartial = false
+			return 0, err
+		}
+		request.Logger.Infof("download.checksum_ok")
+	}
+
+	// 下载过程中计算的SHA256，记录到缓存中供生成锁定文件使用
+	sha256sum := verifier.SHA256()
+
+	// 替换目标文件前处理下载完成的文件，失败时保留原文件
+	if request.BeforeReplace != nil {
+		if err := request.BeforeReplace(tempFile); err != nil {
+			keepPartial = false
+			return 0, err
+		}
 	}
 
 	// 标记下载成功，避免在defer中删除临时文件
 	downloadSuccess = true
 
+	// 使用下载完成的文件替换目标文件
+	if err := replaceFile(tempFile, storePath, keepOldFile, request.Logger); err != nil {
+		return 0, err
+	}
+	os.Remove(metaFile)
+
+	// 更新文件缓存记录，供下次条件请求使用
+	entry := CacheEntry{
+		ETag:         meta.ETag,
+		LastModified: meta.LastModified,
+		Size:         tracker.BytesCount.Load(),
+		URL:          downloadUrl,
+		Tag:          request.Tag,
+		SHA256:       sha256sum,
+	}
+	if err := request.cache().UpdateEntry(storePath, entry); err != nil {
+		request.Logger.Errorf("cache.update_failed", err)
+	}
+
+	return 0, nil
+}
+
+// replaceFile 使用 srcPath 替换 dstPath，keepOld 为 true 时将已存在的目标文件备份为 .old
+func replaceFile(srcPath, dstPath string, keepOld bool, log MsgLogger) error {
 	// 处理旧文件（如果存在）
-	if FileExists(storePath) {
-		if keepOldFile {
+	if FileExists(dstPath) {
+		if keepOld {
 			// 保留旧文件，重命名为.old
-			oldFilePath := storePath + ".old"
+			oldFilePath := dstPath + ".old"
 			// 如果已经存在.old文件，先删除它
 			if FileExists(oldFilePath) {
 				if err := os.Remove(oldFilePath); err != nil {
-					return fmt.Errorf("删除旧的备份文件失败: %w", err)
+					return msgError("replace.remove_backup_failed", err)
 				}
 			}
 			// 重命名当前文件为.old
-			if err := os.Rename(storePath, oldFilePath); err != nil {
-				return fmt.Errorf("备份旧文件失败: %w", err)
+			if err := os.Rename(dstPath, oldFilePath); err != nil {
+				return msgError("replace.backup_failed", err)
 			}
-			fmt.Printf("    已备份旧文件为: %s\n", oldFilePath)
+			log.Infof("replace.backed_up", oldFilePath)
 		} else {
 			// 不保留旧文件，直接删除
-			if err := os.Remove(storePath); err != nil {
-				return fmt.Errorf("错误:删除旧文件失败: %w", err)
+			if err := os.Remove(dstPath); err != nil {
+				return msgError("replace.remove_old_failed", err)
 			}
 		}
 	}
 
 	// 重命名临时文件为最终文件名
-	if err := os.Rename(tempFile, storePath); err != nil {
-		return fmt.Errorf("错误: 重命名临时文件失败: %w", err)
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return msgError("replace.rename_failed", err)
 	}
+	return nil
+}
 
-	// 更新文件下载时间缓存
-	if err := UpdateFileDownloadTime(storePath); err != nil {
-		fmt.Printf("    错误: 更新下载缓存失败: %v\n", err)
+// deadlineError 上下文超过截止时间时返回超时错误，否则返回nil
+func deadlineError(ctx context.Context) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return DownloadError{
+		Message: Msg("download.timeout"),
+		Type:    ErrTimeout,
 	}
+}
 
-	return nil
+// setConditionalHeaders 根据缓存记录设置 If-None-Match / If-Modified-Since 请求头
+// 只有本地文件存在、大小与缓存记录一致且下载地址相同时才发送条件请求
+func setConditionalHeaders(header http.Header, cache CacheStore, storePath, downloadUrl string) {
+	info, err := os.Stat(storePath)
+	if err != nil {
+		return
+	}
+	entry, exists := cache.GetEntry(storePath)
+	if !exists || !entry.HasValidator() || entry.URL != downloadUrl || entry.Size != info.Size() {
+		return
+	}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
 }
 
-func httpGet(client *http.Client, downloadUrl string, err error) (*http.Response, error) {
-	// 创建HTTP请求
-	req, err := http.NewRequest("GET", downloadUrl, nil)
+// httpGet 发送GET请求，header 为附加的请求头（可为nil）
+// 请求头包含 Range 时同时接受206响应
+func httpGet(ctx context.Context, client *http.Client, downloadUrl string, header http.Header) (*http.Response, error) {
+	// 创建HTTP请求，取消 ctx 时中止请求及响应体读取
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// 设置User-Agent以避免某些服务器的限制
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("User-Agent", UserAgent)
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
 
 	// 发送请求
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP请求失败: %w", err)
+		return nil, msgError("http.request_failed", err)
+	}
+
+	// 范围请求返回206视为成功
+	if resp.StatusCode == http.StatusPartialContent && req.Header.Get("Range") != "" {
+		return resp, nil
 	}
 
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		// 条件请求返回304，文件未修改
+		if resp.StatusCode == http.StatusNotModified {
+			return nil, DownloadError{
+				StatusCode: resp.StatusCode,
+				Message:    Msg("http.not_modified"),
+				Type:       ErrNotModified,
+			}
+		}
 		// 对于404错误，返回特殊错误类型
 		if resp.StatusCode == http.StatusNotFound {
 			return nil, DownloadError{
 				StatusCode: resp.StatusCode,
-				Message:    fmt.Sprintf("资源不存在，HTTP状态码: %d (404 Not Found)", resp.StatusCode),
+				Message:    Msg("http.not_found", resp.StatusCode),
 				Type:       ErrResourceNotFound,
 			}
 		}
-		return nil, fmt.Errorf("HTTP请求失败，状态码: %d", resp.StatusCode)
+		// 续传范围无效
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return nil, DownloadError{
+				StatusCode: resp.StatusCode,
+				Message:    Msg("http.range_not_satisfiable", resp.StatusCode),
+				Type:       ErrRangeNotSatisfiable,
+			}
+		}
+		return nil, msgError("http.status_failed", resp.StatusCode)
 	}
 	return resp, nil
 }
@@ -0,0 +1,351 @@
+package downfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discardLogger 丢弃所有日志输出
+type discardLogger struct{}
+
+func (discardLogger) Printf(format string, args ...any) {}
+
+// testRequest 创建不输出日志及进度的下载请求
+func testRequest(t *testing.T, downloadURL, storePath string) downloadRequest {
+	t.Helper()
+	return downloadRequest{
+		URL:       downloadURL,
+		StorePath: storePath,
+		Logger:    MsgLogger{Out: discardLogger{}},
+		Cache:     NewStateStore(t.TempDir(), filepath.Dir(storePath), 0),
+		Reporter:  SilentProgressReporter,
+	}
+}
+
+// recordedRequest 测试服务器收到的请求头
+type recordedRequest struct {
+	Range   string
+	IfRange string
+}
+
+// rangeServer 使用 http.ServeContent 提供 content 的测试服务器，支持 Range/If-Range，etag 可在测试中修改
+type rangeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	etag     string
+	content  []byte
+	requests []recordedRequest
+}
+
+func newRangeServer(t *testing.T, content []byte, etag string) *rangeServer {
+	t.Helper()
+	s := &rangeServer{content: content, etag: etag}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, recordedRequest{Range: r.Header.Get("Range"), IfRange: r.Header.Get("If-Range")})
+		etag := s.etag
+		s.mu.Unlock()
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rangeServer) recorded() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+// writePartial 写入未完成下载文件及续传元数据
+func writePartial(t *testing.T, storePath string, data []byte, meta partialMeta) {
+	t.Helper()
+	partialPath, metaPath := partialPaths(storePath)
+	if err := os.WriteFile(partialPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := savePartialMeta(metaPath, &meta); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 1000))
+
+	tests := []struct {
+		name       string
+		partial    []byte
+		totalSize  int64
+		serverETag string
+		wantRange  string
+		wantCalls  int
+	}{
+		{
+			name:       "206续传",
+			partial:    content[:4000],
+			totalSize:  int64(len(content)),
+			serverETag: `"v1"`,
+			wantRange:  "bytes=4000-",
+			wantCalls:  1,
+		},
+		{
+			name:       "资源变化时返回200从头下载",
+			partial:    []byte(strings.Repeat("x", 4000)),
+			totalSize:  int64(len(content)),
+			serverETag: `"v2"`,
+			wantRange:  "bytes=4000-",
+			wantCalls:  1,
+		},
+		{
+			name:       "416时删除未完成文件后从头下载",
+			partial:    bytes.Repeat([]byte("x"), len(content)+10),
+			totalSize:  -1,
+			serverETag: `"v1"`,
+			wantRange:  "bytes=10010-",
+			wantCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRangeServer(t, content, tt.serverETag)
+			storePath := filepath.Join(t.TempDir(), "file.bin")
+			writePartial(t, storePath, tt.partial, partialMeta{URL: server.URL, ETag: `"v1"`, TotalSize: tt.totalSize})
+
+			request := testRequest(t, server.URL, storePath)
+			request.Checksums = map[string]string{AlgoSHA256: sha256Hex(string(content))}
+			if _, err := downloadFile(context.Background(), server.Client(), request); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(storePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("content mismatch: got %d bytes", len(got))
+			}
+			requests := server.recorded()
+			if len(requests) != tt.wantCalls {
+				t.Fatalf("requests = %d, want %d", len(requests), tt.wantCalls)
+			}
+			if requests[0].Range != tt.wantRange || requests[0].IfRange != `"v1"` {
+				t.Errorf("first request = %+v, want Range %q with If-Range", requests[0], tt.wantRange)
+			}
+			if tt.wantCalls > 1 && requests[1].Range != "" {
+				t.Errorf("restart request sent Range %q", requests[1].Range)
+			}
+			for _, path := range []string{storePath + PartialSuffix, storePath + PartialMetaSuffix, storePath + PartialLockSuffix} {
+				if FileExists(path) {
+					t.Errorf("%s left behind", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadFileWithoutValidatorDoesNotResume(t *testing.T) {
+	content := []byte(strings.Repeat("a", 2048))
+	server := newRangeServer(t, content, `"v1"`)
+	storePath := filepath.Join(t.TempDir(), "file.bin")
+	writePartial(t, storePath, content[:100], partialMeta{URL: server.URL, TotalSize: int64(len(content))})
+
+	if _, err := downloadFile(context.Background(), server.Client(), testRequest(t, server.URL, storePath)); err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.recorded(); len(requests) != 1 || requests[0].Range != "" {
+		t.Errorf("requests = %+v, want a single full request", requests)
+	}
+}
+
+func TestDownloadFileWaitsForPartialLock(t *testing.T) {
+	content := []byte("locked content")
+	server := newRangeServer(t, content, `"v1"`)
+	storePath := filepath.Join(t.TempDir(), "file.bin")
+
+	// 模拟其他进程正在下载同一文件
+	unlock, err := lockPartial(context.Background(), storePath, MsgLogger{Out: discardLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 锁被持有时超过截止时间返回超时错误，不发送请求
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	_, err = downloadFile(ctx, server.Client(), testRequest(t, server.URL, storePath))
+	cancel()
+	var downloadErr DownloadError
+	if !errors.As(err, &downloadErr) || downloadErr.Type != ErrTimeout {
+		t.Fatalf("err = %v, want %s", err, ErrTimeout)
+	}
+	if requests := server.recorded(); len(requests) != 0 {
+		t.Fatalf("requests sent while partial is locked: %+v", requests)
+	}
+
+	// 锁释放后继续下载
+	done := make(chan error, 1)
+	go func() {
+		_, err := downloadFile(context.Background(), server.Client(), testRequest(t, server.URL, storePath))
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if requests := server.recorded(); len(requests) != 0 {
+		t.Fatalf("requests sent while partial is locked: %+v", requests)
+	}
+	unlock()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("download did not continue after the lock was released")
+	}
+	if got, _ := os.ReadFile(storePath); !bytes.Equal(got, content) {
+		t.Errorf("content = %q, want %q", got, content)
+	}
+}
+
+func TestCleanupSkipsLockedPartial(t *testing.T) {
+	dir := t.TempDir()
+	storePath := filepath.Join(dir, "file.bin")
+	if err := os.WriteFile(storePath+PartialSuffix, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockPartial(context.Background(), storePath, MsgLogger{Out: discardLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := RemovePartialDownloads(dir); err != nil || removed != 0 {
+		t.Fatalf("RemovePartialDownloads() = %d, %v; want 0 while locked", removed, err)
+	}
+	if err := CleanupIncompleteDownloads(dir, MsgLogger{Out: discardLogger{}}); err != nil {
+		t.Fatal(err)
+	}
+	if !FileExists(storePath + PartialSuffix) {
+		t.Fatal("locked partial removed")
+	}
+
+	unlock()
+	if removed, err := RemovePartialDownloads(dir); err != nil || removed != 1 {
+		t.Fatalf("RemovePartialDownloads() = %d, %v; want 1 after unlock", removed, err)
+	}
+}
+
+func TestProcessItemNotModified(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	var mu sync.Mutex
+	var ifNoneMatch, ifModifiedSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ifNoneMatch, ifModifiedSince = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("new content"))
+	}))
+	defer server.Close()
+
+	d := testDownloader(t)
+	item := DownItem{Module: "a", FileName: "a.bin", KeepUpdated: true, DownloadURLs: []string{server.URL + "/a.bin"}}
+	storePath := GetItemFilePath(item.FileName, d.outputDir)
+	content := []byte("old content")
+	if err := os.WriteFile(storePath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存记录的下载时间已过期
+	store := d.cache.(*FileCache)
+	entry := CacheEntry{ETag: etag, LastModified: lastModified, Size: int64(len(content)), URL: item.DownloadURLs[0]}
+	if err := store.UpdateEntry(storePath, entry); err != nil {
+		t.Fatal(err)
+	}
+	key, err := store.key(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := store.Load()
+	expired := time.Now().Add(-48 * time.Hour)
+	entry = cache.Files[key]
+	entry.DownloadTime = expired
+	cache.Files[key] = entry
+	if err := store.Save(cache); err != nil {
+		t.Fatal(err)
+	}
+
+	result := d.DownloadItem(context.Background(), item)
+	if result.Status != StatusNotModified {
+		t.Errorf("status = %s, want %s", result.Status, StatusNotModified)
+	}
+	mu.Lock()
+	if ifNoneMatch != etag || ifModifiedSince != lastModified {
+		t.Errorf("If-None-Match = %q, If-Modified-Since = %q, want %q, %q", ifNoneMatch, ifModifiedSince, etag, lastModified)
+	}
+	mu.Unlock()
+	if data, err := os.ReadFile(storePath); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("local file = %q (%v), want %q", data, err, content)
+	}
+	updated, ok := store.GetEntry(storePath)
+	if !ok || !updated.DownloadTime.After(expired.Add(time.Hour)) {
+		t.Errorf("download time = %v, want refreshed", updated.DownloadTime)
+	}
+	if updated.ETag != etag || updated.LastModified != lastModified {
+		t.Errorf("cache entry = %+v, want validators kept", updated)
+	}
+}
+
+func TestDownloadFileRecordsSHA256(t *testing.T) {
+	content := []byte(strings.Repeat("sha256-content-", 1000))
+	tests := []struct {
+		name     string
+		segments int
+		partial  int
+	}{
+		{name: "普通下载"},
+		{name: "分段下载", segments: 4},
+		{name: "续传下载", partial: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMinSegmentSize(t, 1024)
+			server := newRangeServer(t, content, `"v1"`)
+			storePath := filepath.Join(t.TempDir(), "file.bin")
+			if tt.partial > 0 {
+				writePartial(t, storePath, content[:tt.partial], partialMeta{URL: server.URL, ETag: `"v1"`, TotalSize: int64(len(content))})
+			}
+
+			// 未配置校验值时同样记录下载文件的SHA256
+			request := testRequest(t, server.URL, storePath)
+			request.Segments = tt.segments
+			if _, err := downloadFile(context.Background(), server.Client(), request); err != nil {
+				t.Fatal(err)
+			}
+			ranged := 0
+			for _, r := range server.recorded() {
+				if r.Range != "" {
+					ranged++
+				}
+			}
+			if want := tt.segments > 1 || tt.partial > 0; (ranged > 0) != want {
+				t.Errorf("range requests = %d, want ranged=%v", ranged, want)
+			}
+			entry, ok := request.Cache.GetEntry(storePath)
+			if !ok || entry.SHA256 != sha256Hex(string(content)) {
+				t.Errorf("cached sha256 = %q, want %q", entry.SHA256, sha256Hex(string(content)))
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package downfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// LockVersion 锁定文件格式版本
+const LockVersion = 1
+
+// LockEntry 锁定文件中单个下载项的下载结果
+type LockEntry struct {
+	Group        string `json:"group"`
+	Module       string `json:"module"`
+	FileName     string `json:"filename"`
+	URL          string `json:"url"`                     // 实际下载的地址（已解析 GitHub Release 及改写规则）
+	Size         int64  `json:"size"`                    // 文件大小
+	SHA256       string `json:"sha256"`                  // 文件的SHA256
+	ETag         string `json:"etag,omitempty"`          // 服务器返回的ETag
+	LastModified string `json:"last_modified,omitempty"` // 服务器返回的Last-Modified
+	Tag          string `json:"tag,omitempty"`           // GitHub Release 标签
+}
+
+// LockFile 锁定文件，记录每个下载项确定的下载地址、大小及校验值，用于重现下载结果
+// 下载项按文件名索引（不同下载项的文件路径不能相同），内容不包含时间，相同结果生成的文件完全一致
+type LockFile struct {
+	Version int         `json:"version"`
+	Items   []LockEntry `json:"items"`
+}
+
+// LoadLockFile 读取锁定文件，文件不存在时返回空的锁定文件
+func LoadLockFile(path string) (*LockFile, error) {
+	lock := &LockFile{Version: LockVersion}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, msgError("lock.read_failed", err)
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, msgError("lock.parse_failed", path, err)
+	}
+	if lock.Version > LockVersion {
+		return nil, msgError("lock.unsupported_version", lock.Version, LockVersion)
+	}
+	return lock, nil
+}
+
+// Save 按配置组、模块名排序后写入锁定文件，先写入临时文件再重命名，避免中断时留下不完整的文件
+func (l *LockFile) Save(path string) error {
+	l.Version = LockVersion
+	sort.SliceStable(l.Items, func(i, j int) bool {
+		if l.Items[i].Group != l.Items[j].Group {
+			return l.Items[i].Group < l.Items[j].Group
+		}
+		return l.Items[i].Module < l.Items[j].Module
+	})
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return msgError("lock.write_failed", err)
+	}
+	if err := MakeDirs(path, true); err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(data, '\n'), 0644); err != nil {
+		return msgError("lock.write_failed", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return msgError("lock.write_failed", err)
+	}
+	return nil
+}
+
+// Entry 获取下载项文件名对应的锁定记录
+func (l *LockFile) Entry(fileName string) (LockEntry, bool) {
+	for _, entry := range l.Items {
+		if entry.FileName == fileName {
+			return entry, true
+		}
+	}
+	return LockEntry{}, false
+}
+
+// Update 使用新的锁定记录替换同一文件的旧记录，并删除不属于 tasks 中任何下载项的记录
+func (l *LockFile) Update(entries []LockEntry, tasks []DownTask) {
+	updated := make(map[string]LockEntry, len(entries))
+	for _, entry := range entries {
+		updated[entry.FileName] = entry
+	}
+	items := make([]LockEntry, 0, len(tasks))
+	for _, task := range tasks {
+		if entry, ok := updated[task.Item.FileName]; ok {
+			items = append(items, entry)
+		} else if entry, ok := l.Entry(task.Item.FileName); ok {
+			entry.Group, entry.Module = task.Group, task.Item.Module
+			items = append(items, entry)
+		}
+	}
+	l.Items = items
+}
+
+// LockEntries 根据下载结果生成锁定记录，文件信息来自本地文件及下载缓存，缓存中没有下载地址时使用下载结果中的地址
+// 缓存记录的文件大小与本地文件一致时使用下载时记录的SHA256，否则重新计算
+// 无法确定下载地址的下载项（如跳过的已有文件且缓存中没有记录）输出警告后不生成记录，锁定文件中保留原有记录
+func (d *Downloader) LockEntries(results []DownResult) ([]LockEntry, error) {
+	entries := make([]LockEntry, 0, len(results))
+	for _, result := range results {
+		if !result.Success() {
+			continue
+		}
+		storePath := GetItemFilePath(result.FileName, d.outputDir)
+		info, err := os.Stat(storePath)
+		if err != nil {
+			return nil, msgError("lock.stat_failed", storePath, err)
+		}
+		entry := LockEntry{
+			Group:    result.Group,
+			Module:   result.Module,
+			FileName: result.FileName,
+			URL:      result.URL,
+			Size:     info.Size(),
+		}
+		cached, exists := d.cache.GetEntry(storePath)
+		if exists {
+			if cached.URL != "" {
+				entry.URL = cached.URL
+			}
+			entry.ETag, entry.LastModified, entry.Tag = cached.ETag, cached.LastModified, cached.Tag
+		}
+		if entry.URL == "" {
+			d.log(result.Module).Warnf("lock.no_url_skip", result.FileName)
+			continue
+		}
+		if exists && cached.SHA256 != "" && cached.Size == entry.Size {
+			entry.SHA256 = cached.SHA256
+		} else if entry.SHA256, err = fileSHA256(storePath); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// lockedItem 将下载项限定为锁定记录中的下载地址及SHA256，不再解析 GitHub Release 或使用其他下载源
+func (d *Downloader) lockedItem(item DownItem) (DownItem, error) {
+	entry, ok := d.lock.Entry(item.FileName)
+	if !ok {
+		return item, msgError("lock.item_missing", item.Module, item.FileName)
+	}
+	if entry.URL == "" || entry.SHA256 == "" {
+		return item, msgError("lock.item_incomplete", item.Module)
+	}
+	item.DownloadURLs = []string{entry.URL}
+	item.GitHubRelease = ""
+	item.SHA256 = entry.SHA256
+	item.SHA512, item.MD5, item.ChecksumURL, item.ChecksumsFile = "", "", "", ""
+	item.MirrorStrategy = MirrorOrdered
+	item.Race = false
+	item.SegmentMirrors = false
+	return item, nil
+}
+
+// fileSHA256 计算文件的SHA256
+func fileSHA256(path string) (string, error) {
+	hash := sha256.New()
+	if err := hashFile(path, hash); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fileMatchesSHA256 判断文件的SHA256是否与期望值一致
+func fileMatchesSHA256(path, expected string) bool {
+	sum, err := fileSHA256(path)
+	return err == nil && sum == normalizeChecksum(expected)
+}
@@ -0,0 +1,62 @@
+package downfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockEntriesSkipsUnknownURL(t *testing.T) {
+	d := testDownloader(t)
+	for _, name := range []string{"known.bin", "unknown.bin"} {
+		if err := os.WriteFile(filepath.Join(d.outputDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := []DownResult{
+		{Group: "g", Module: "known", FileName: "known.bin", URL: "https://example.com/known.bin", Status: StatusDownloaded},
+		{Group: "g", Module: "unknown", FileName: "unknown.bin", Status: StatusSkipped},
+	}
+	entries, err := d.LockEntries(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Module != "known" || entries[0].SHA256 != sha256Hex("known.bin") {
+		t.Fatalf("entries = %+v, want only the item with a URL", entries)
+	}
+
+	// 没有新记录的下载项保留锁定文件中的原有记录
+	old := LockEntry{Group: "g", Module: "unknown", FileName: "unknown.bin", URL: "https://example.com/old.bin", SHA256: "abc"}
+	lock := &LockFile{Items: []LockEntry{old}}
+	lock.Update(entries, []DownTask{
+		{Group: "g", Item: DownItem{Module: "known", FileName: "known.bin"}},
+		{Group: "g", Item: DownItem{Module: "unknown", FileName: "unknown.bin"}},
+	})
+	if entry, ok := lock.Entry("unknown.bin"); !ok || entry.URL != old.URL {
+		t.Errorf("entry = %+v, want the previous lock entry kept", entry)
+	}
+	for _, entry := range lock.Items {
+		if entry.URL == "" {
+			t.Errorf("lock entry without URL: %+v", entry)
+		}
+	}
+}
+
+func TestNeedsUpdateLockedHonorsForce(t *testing.T) {
+	content := "locked"
+	lock := &LockFile{Items: []LockEntry{{FileName: "a.bin", URL: "https://example.com/a.bin", SHA256: sha256Hex(content)}}}
+	for _, force := range []bool{false, true} {
+		d := testDownloader(t, WithLock(lock), WithForceUpdate(force))
+		storePath := filepath.Join(d.outputDir, "a.bin")
+		if err := os.WriteFile(storePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		item, err := d.lockedItem(DownItem{Module: "a", FileName: "a.bin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.ItemStatus(item).NeedsUpdate; got != force {
+			t.Errorf("force=%v: NeedsUpdate = %v, want %v", force, got, force)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package downfile
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Logger 日志输出接口
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// LevelLogger 支持日志级别及结构化属性的日志输出接口
+// MsgLogger 的输出目标实现此接口时，由输出目标自行按级别过滤
+type LevelLogger interface {
+	Log(level slog.Level, msg string, args ...any)
+}
+
+// SlogLogger 使用 log/slog 输出日志
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger 创建使用 log/slog 输出的日志
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{Logger: logger}
+}
+
+// Printf 以 Info 级别输出日志
+func (l *SlogLogger) Printf(format string, args ...any) {
+	l.Logger.Info(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+// Log 输出指定级别的日志
+func (l *SlogLogger) Log(level slog.Level, msg string, args ...any) {
+	l.Logger.Log(context.Background(), level, strings.TrimSpace(msg), args...)
+}
+
+// ParseLogLevel 解析日志级别名称（debug|info|warn|error）
+func ParseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(name))
+	return level, err
+}
+
+// MsgLogger 使用消息目录输出分级日志，并发下载时为每行添加下载项前缀
+type MsgLogger struct {
+	Prefix string     // 日志前缀（下载项名称）
+	Out    Logger     // 日志输出目标，为nil时输出到终端
+	Level  slog.Level // 最低输出级别，输出目标实现 LevelLogger 时由其自行过滤
+}
+
+// Debugf 输出调试日志
+func (l MsgLogger) Debugf(key string, args ...any) {
+	l.log(slog.LevelDebug, key, args...)
+}
+
+// Infof 输出普通日志
+func (l MsgLogger) Infof(key string, args ...any) {
+	l.log(slog.LevelInfo, key, args...)
+}
+
+// Warnf 输出警告日志
+func (l MsgLogger) Warnf(key string, args ...any) {
+	l.log(slog.LevelWarn, key, args...)
+}
+
+// Errorf 输出错误日志
+func (l MsgLogger) Errorf(key string, args ...any) {
+	l.log(slog.LevelError, key, args...)
+}
+
+// log 格式化消息并输出
+func (l MsgLogger) log(level slog.Level, key string, args ...any) {
+	out := l.Out
+	if out == nil {
+		out = console
+	}
+	msg := Msg(key, args...)
+
+	if levelLogger, ok := out.(LevelLogger); ok {
+		var attrs []any
+		if l.Prefix != "" {
+			attrs = append(attrs, "item", l.Prefix)
+		}
+		levelLogger.Log(level, msg, attrs...)
+		return
+	}
+
+	if level < l.Level {
+		return
+	}
+	if l.Prefix != "" {
+		trimmed := strings.TrimLeft(msg, " ")
+		indent := msg[:len(msg)-len(trimmed)]
+		msg = indent + "[" + l.Prefix + "] " + trimmed
+	}
+	out.Printf("%s\n", msg)
+}
@@ -0,0 +1,97 @@
+package downfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 支持的语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// 消息目录，内置消息之外可通过 RegisterMessages 注册
+var (
+	messagesMu sync.RWMutex
+	messages   = builtinMessages
+	language   atomic.Value
+)
+
+func init() {
+	language.Store(LangZH)
+}
+
+// RegisterMessages 注册（或覆盖）指定语言的消息
+func RegisterMessages(lang string, catalog map[string]string) {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	if messages[lang] == nil {
+		messages[lang] = make(map[string]string)
+	}
+	for key, format := range catalog {
+		messages[lang][key] = format
+	}
+}
+
+// SetLanguage 设置消息语言，支持 zh、en 及 en_US.UTF-8 等形式，无法识别时使用中文
+func SetLanguage(lang string) {
+	language.Store(normalizeLanguage(lang))
+}
+
+// Language 获取当前消息语言
+func Language() string {
+	return language.Load().(string)
+}
+
+// DetectLanguage 根据 LC_ALL、LC_MESSAGES、LANG 环境变量判断消息语言，未设置时使用中文
+func DetectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" && value != "C" && value != "POSIX" {
+			return normalizeLanguage(value)
+		}
+	}
+	return LangZH
+}
+
+// normalizeLanguage 规范化语言名称
+func normalizeLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	if strings.HasPrefix(lang, LangEN) {
+		return LangEN
+	}
+	return LangZH
+}
+
+// msgFormat 获取消息的格式字符串，当前语言缺少该消息时使用中文，都没有时返回消息键
+func msgFormat(key string) string {
+	messagesMu.RLock()
+	defer messagesMu.RUnlock()
+	if format, ok := messages[Language()][key]; ok {
+		return format
+	}
+	if format, ok := messages[LangZH][key]; ok {
+		return format
+	}
+	return key
+}
+
+// Msg 使用当前语言格式化消息
+func Msg(key string, args ...any) string {
+	if len(args) == 0 {
+		return msgFormat(key)
+	}
+	return fmt.Sprintf(msgFormat(key), args...)
+}
+
+// msgError 使用当前语言创建错误，格式字符串支持 %w
+func msgError(key string, args ...any) error {
+	if len(args) == 0 {
+		return errors.New(msgFormat(key))
+	}
+	return fmt.Errorf(msgFormat(key), args...)
+}
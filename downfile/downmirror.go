@@ -0,0 +1,160 @@
+package downfile
+
+import (
+	"errors"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 下载源排序策略
+const (
+	MirrorOrdered    = "ordered"    // 按配置顺序
+	MirrorFastest    = "fastest"    // 按历史平均下载速度从快到慢
+	MirrorHealthiest = "healthiest" // 按历史成功率从高到低，相同时延迟低的优先
+	MirrorRandom     = "random"     // 随机顺序
+)
+
+// mirrorSmoothAlpha 平均延迟及速度的指数平滑系数
+const mirrorSmoothAlpha = 0.3
+
+// HostStats 下载源主机的历史下载统计
+type HostStats struct {
+	Successes   int       `json:"successes"`            // 成功次数
+	Failures    int       `json:"failures"`             // 失败次数
+	Latency     float64   `json:"latency_ms,omitempty"` // 平均响应延迟（毫秒）
+	Speed       float64   `json:"speed,omitempty"`      // 平均下载速度 (bytes/second)
+	LastSuccess time.Time `json:"last_success"`         // 最后成功时间
+	LastFailure time.Time `json:"last_failure"`         // 最后失败时间
+}
+
+// SuccessRate 获取平滑后的成功率，没有记录时为0.5
+func (s HostStats) SuccessRate() float64 {
+	return float64(s.Successes+1) / float64(s.Successes+s.Failures+2)
+}
+
+// record 记录一次下载结果，latency 或 speed 小于等于0时不更新对应的平均值
+func (s *HostStats) record(success bool, latency time.Duration, speed float64) {
+	now := time.Now()
+	if success {
+		s.Successes++
+		s.LastSuccess = now
+	} else {
+		s.Failures++
+		s.LastFailure = now
+	}
+	if latency > 0 {
+		s.Latency = smoothValue(s.Latency, float64(latency.Milliseconds()))
+	}
+	if speed > 0 {
+		s.Speed = smoothValue(s.Speed, speed)
+	}
+}
+
+// smoothValue 计算指数平滑平均值，旧值为0时直接使用新值
+func smoothValue(old, value float64) float64 {
+	if old <= 0 {
+		return value
+	}
+	return old*(1-mirrorSmoothAlpha) + value*mirrorSmoothAlpha
+}
+
+// recordMirrorResult 记录下载源的一次下载结果，文件未修改（304）视为成功
+func recordMirrorResult(cache CacheStore, downloadUrl string, elapsed, latency time.Duration, transferred int64, err error, log MsgLogger) {
+	var downloadErr DownloadError
+	success := err == nil || (errors.As(err, &downloadErr) && downloadErr.Type == ErrNotModified)
+	speed := 0.0
+	if transferred > 0 && elapsed > 0 {
+		speed = float64(transferred) / elapsed.Seconds()
+	}
+	if err := cache.RecordHost(downloadUrl, success, latency, speed); err != nil {
+		log.Warnf("mirror.record_failed", err)
+	}
+}
+
+// mirrorHost 获取下载地址的主机名，用作统计记录的键
+func mirrorHost(downloadUrl string) string {
+	parsed, err := url.Parse(downloadUrl)
+	if err != nil || parsed.Host == "" {
+		return downloadUrl
+	}
+	return parsed.Host
+}
+
+// ValidMirrorStrategy 判断是否是支持的下载源排序策略，空字符串表示使用默认策略
+func ValidMirrorStrategy(strategy string) bool {
+	switch strategy {
+	case "", MirrorOrdered, MirrorFastest, MirrorHealthiest, MirrorRandom:
+		return true
+	}
+	return false
+}
+
+// orderMirrors 按排序策略返回下载地址的尝试顺序，没有统计记录的下载源保持配置中的相对顺序
+func orderMirrors(urls []string, strategy string, hosts map[string]HostStats) []string {
+	ordered := append([]string(nil), urls...)
+	switch strategy {
+	case MirrorFastest:
+		// 有成功记录的下载源按速度排序，其余的排在后面
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return mirrorSpeed(hosts, ordered[i]) > mirrorSpeed(hosts, ordered[j])
+		})
+	case MirrorHealthiest:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			a, b := hosts[mirrorHost(ordered[i])], hosts[mirrorHost(ordered[j])]
+			if a.SuccessRate() != b.SuccessRate() {
+				return a.SuccessRate() > b.SuccessRate()
+			}
+			// 成功率相同时延迟低的优先，没有延迟记录的排在后面
+			return a.Latency > 0 && (b.Latency <= 0 || a.Latency < b.Latency)
+		})
+	case MirrorRandom:
+		rand.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+	return ordered
+}
+
+// itemMirrors 获取下载项的下载地址（转换GitHub URL并追加改写规则生成的候选地址，使用锁定文件时不改写），并按下载源排序策略排序
+func (d *Downloader) itemMirrors(item DownItem, log MsgLogger) []string {
+	urls := make([]string, 0, len(item.DownloadURLs))
+	for _, url := range item.DownloadURLs {
+		// 处理GitHub URL
+		downloadURL := url
+		if strings.Contains(url, "github.com") && strings.Contains(url, "/blob/") {
+			downloadURL = ConvertGitHubURL(url)
+			log.Debugf("item.github_convert", url, downloadURL)
+		}
+		urls = append(urls, downloadURL)
+	}
+	if d.lock == nil {
+		urls = rewriteURLs(urls, d.rewriteRules)
+	}
+
+	strategy := item.MirrorStrategy
+	if strategy == "" {
+		strategy = d.mirrorStrategy
+	}
+	if !ValidMirrorStrategy(strategy) {
+		log.Warnf("mirror.unknown_strategy", strategy)
+		return urls
+	}
+	if strategy == "" || strategy == MirrorOrdered || len(urls) < 2 {
+		return urls
+	}
+	ordered := orderMirrors(urls, strategy, d.cache.HostStats())
+	log.Debugf("mirror.order", strategy, strings.Join(ordered, ", "))
+	return ordered
+}
+
+// mirrorSpeed 获取下载源的平均下载速度，没有成功记录时为0
+func mirrorSpeed(hosts map[string]HostStats, downloadUrl string) float64 {
+	stats := hosts[mirrorHost(downloadUrl)]
+	if stats.Successes == 0 {
+		return 0
+	}
+	return stats.Speed
+}
@@ -0,0 +1,218 @@
+package downfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 未完成下载文件后缀
+const (
+	// PartialSuffix 未完成下载的数据文件后缀
+	PartialSuffix = ".download"
+	// PartialMetaSuffix 未完成下载的元数据文件后缀
+	PartialMetaSuffix = ".download.json"
+	// PartialLockSuffix 未完成下载的锁文件后缀，下载过程中持有文件锁
+	PartialLockSuffix = ".download.lock"
+)
+
+// partialLockInterval 其他进程正在下载同一文件时检查其是否完成的间隔
+const partialLockInterval = time.Second
+
+// partialMeta 未完成下载的元数据，用于断点续传时校验服务器上的资源是否变化
+type partialMeta struct {
+	URL          string    `json:"url"`           // 下载地址
+	ETag         string    `json:"etag"`          // 服务器返回的ETag
+	LastModified string    `json:"last_modified"` // 服务器返回的Last-Modified
+	TotalSize    int64     `json:"total_size"`    // 文件总大小，未知时为-1
+	UpdatedAt    time.Time `json:"updated_at"`    // 最后更新时间
+}
+
+// partialPaths 获取下载项的未完成下载文件路径及元数据文件路径
+func partialPaths(storePath string) (string, string) {
+	return storePath + PartialSuffix, storePath + PartialMetaSuffix
+}
+
+// lockPartial 获取未完成下载文件的文件锁，在释放前其他进程不会写入同一个未完成下载文件
+// 其他进程正在下载同一文件时等待其完成（ctx 结束时返回错误）；无法创建锁文件时输出警告后不加锁继续下载
+func lockPartial(ctx context.Context, storePath string, log MsgLogger) (func(), error) {
+	lockPath := storePath + PartialLockSuffix
+	waiting := false
+	for {
+		file, err := tryLockFile(lockPath)
+		if err != nil {
+			log.Warnf("partial.lock_failed", err)
+			return func() {}, nil
+		}
+		if file != nil {
+			// 持有者释放锁前会删除锁文件，获取到已删除的锁文件时重新获取
+			if sameFile(file, lockPath) {
+				return func() {
+					os.Remove(lockPath)
+					unlockFile(file)
+				}, nil
+			}
+			unlockFile(file)
+			continue
+		}
+		if !waiting {
+			log.Infof("partial.locked_wait", filepath.Base(storePath))
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			if err := deadlineError(ctx); err != nil {
+				return nil, err
+			}
+			return nil, ctx.Err()
+		case <-time.After(partialLockInterval):
+		}
+	}
+}
+
+// partialInUse 判断未完成下载文件是否正在被其他进程（或当前进程的其他下载）写入
+func partialInUse(partialPath string) bool {
+	lockPath := strings.TrimSuffix(partialPath, PartialSuffix) + PartialLockSuffix
+	if !FileExists(lockPath) {
+		return false
+	}
+	file, err := tryLockFile(lockPath)
+	if err != nil || file == nil {
+		return err == nil
+	}
+	// 锁文件已无人持有（如进程异常退出），删除遗留的锁文件
+	if sameFile(file, lockPath) {
+		os.Remove(lockPath)
+	}
+	unlockFile(file)
+	return false
+}
+
+// sameFile 判断打开的文件是否仍是 path 指向的文件
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(opened, current)
+}
+
+// validator 获取用于 If-Range 的校验标识，弱ETag不能用于范围请求
+func (m *partialMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// loadPartialMeta 读取未完成下载的元数据
+func loadPartialMeta(metaPath string) (*partialMeta, error) {
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	var meta partialMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// savePartialMeta 保存未完成下载的元数据
+func savePartialMeta(metaPath string, meta *partialMeta) error {
+	meta.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath, data, 0644)
+}
+
+// removePartial 删除未完成下载文件及其元数据
+func removePartial(storePath string) {
+	partialPath, metaPath := partialPaths(storePath)
+	os.Remove(partialPath)
+	os.Remove(metaPath)
+}
+
+// resumeOffset 检查是否可以从已下载的部分继续下载，返回已下载的字节数及 If-Range 校验标识
+// 只有下载地址相同且存在有效校验标识时才允许续传
+func resumeOffset(storePath, downloadUrl string) (int64, string) {
+	partialPath, metaPath := partialPaths(storePath)
+
+	info, err := os.Stat(partialPath)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+
+	meta, err := loadPartialMeta(metaPath)
+	if err != nil || meta.URL != downloadUrl || meta.validator() == "" {
+		return 0, ""
+	}
+
+	if meta.TotalSize > 0 && info.Size() >= meta.TotalSize {
+		// 已下载部分不小于文件总大小，说明数据异常，重新下载
+		return 0, ""
+	}
+	return info.Size(), meta.validator()
+}
+
+// parseContentRangeStart 解析 Content-Range 响应头中的起始位置
+func parseContentRangeStart(contentRange string) (int64, error) {
+	// 格式: bytes <start>-<end>/<total>
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return 0, msgError("partial.invalid_content_range", contentRange)
+	}
+	return start, nil
+}
+
+// openPartialFile 根据响应状态打开未完成下载文件
+// 服务器返回206时追加写入并将已有内容写入 hashWriter，返回200时从头开始写入
+func openPartialFile(partialPath string, resp *http.Response, offset int64, hashWriter io.Writer) (*os.File, int64, error) {
+	if resp.StatusCode == http.StatusPartialContent {
+		start, err := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, 0, err
+		}
+		if start != offset {
+			return nil, 0, msgError("partial.offset_mismatch", start, offset)
+		}
+
+		out, err := os.OpenFile(partialPath, os.O_RDWR, 0644)
+		if err != nil {
+			return nil, 0, msgError("partial.open_failed", err)
+		}
+
+		// 已下载部分需要参与校验值计算
+		if hashWriter != nil {
+			if _, err := io.CopyN(hashWriter, out, offset); err != nil {
+				out.Close()
+				return nil, 0, msgError("partial.read_failed", err)
+			}
+		}
+		if _, err := out.Seek(offset, io.SeekStart); err != nil {
+			out.Close()
+			return nil, 0, msgError("partial.seek_failed", err)
+		}
+		if err := out.Truncate(offset); err != nil {
+			out.Close()
+			return nil, 0, msgError("partial.truncate_failed", err)
+		}
+		return out, offset, nil
+	}
+
+	out, err := os.Create(partialPath)
+	if err != nil {
+		return nil, 0, msgError("partial.create_failed", err)
+	}
+	return out, 0, nil
+}
@@ -0,0 +1,199 @@
+package downfile
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// 下载计划中下载项的处理方式
+const (
+	PlanDownload = "download" // 本地文件不存在，下载文件
+	PlanReplace  = "replace"  // 本地文件存在且需要更新，服务器文件变化时替换
+	PlanSkip     = "skip"     // 本地文件存在且不需要更新，跳过
+)
+
+// 下载计划中处理方式的原因
+const (
+	ReasonMissing          = "missing"           // 本地文件不存在
+	ReasonForce            = "force"             // 强制更新
+	ReasonKeepUpdated      = "keep-updated"      // keep-updated 且缓存记录过期或可以条件请求
+	ReasonCached           = "cached"            // keep-updated 但缓存记录未过期
+	ReasonExists           = "exists"            // 本地文件存在且未设置 keep-updated
+	ReasonReleaseUnchanged = "release-unchanged" // GitHub Release 标签及文件大小与缓存记录一致
+	ReasonLocked           = "locked"            // 本地文件的SHA256与锁定记录一致
+	ReasonLockMismatch     = "lock-mismatch"     // 本地文件的SHA256与锁定记录不一致
+)
+
+// PlanMirror 下载计划中下载源的探测结果
+type PlanMirror struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"` // HTTP状态码，请求失败时为0
+	Size   int64  `json:"size"`             // 文件大小，未知时为-1
+	Error  string `json:"error,omitempty"`
+}
+
+// PlanItem 下载项的下载计划
+type PlanItem struct {
+	Group     string       `json:"group"`
+	Module    string       `json:"module"`
+	FileName  string       `json:"filename"`
+	Path      string       `json:"path"`              // 下载文件路径
+	Action    string       `json:"action"`            // 处理方式：download|replace|skip
+	Reason    string       `json:"reason"`            // 处理方式的原因
+	KeepOld   bool         `json:"keep_old"`          // 替换时是否保留旧文件
+	LocalSize int64        `json:"local_size"`        // 本地文件大小，不存在时为-1
+	Release   string       `json:"release,omitempty"` // GitHub 仓库（github-release 下载项）
+	Tag       string       `json:"tag,omitempty"`     // 解析出的 Release 标签（仅探测时）
+	URLs      []string     `json:"urls"`              // 按尝试顺序排列的下载地址
+	Mirrors   []PlanMirror `json:"mirrors,omitempty"` // 下载源探测结果（仅探测时）
+	Error     string       `json:"error,omitempty"`   // 解析或探测失败原因
+}
+
+// OK 判断下载计划是否正常：解析没有失败，有下载地址（或 GitHub Release），探测时至少有一个可用的下载源
+func (p PlanItem) OK() bool {
+	if p.Error != "" || (len(p.URLs) == 0 && p.Release == "") {
+		return false
+	}
+	if p.Mirrors == nil {
+		return true
+	}
+	for _, mirror := range p.Mirrors {
+		if mirror.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan 生成下载计划：解析每个下载项的下载地址、目标路径及处理方式，不写入任何文件，也不修改下载缓存
+// probe 为 true 时解析 GitHub Release 的下载地址，并使用HEAD请求探测所有下载源的状态及文件大小
+func (d *Downloader) Plan(ctx context.Context, tasks []DownTask, probe bool) []PlanItem {
+	plans := make([]PlanItem, len(tasks))
+	for i, task := range tasks {
+		plans[i] = d.planItem(ctx, task, probe, d.log(task.Item.Module))
+	}
+	return plans
+}
+
+// planItem 生成单个下载项的下载计划，判断逻辑与 processItem 一致
+func (d *Downloader) planItem(ctx context.Context, task DownTask, probe bool, log MsgLogger) PlanItem {
+	item := task.Item
+	var lockErr error
+	if d.lock != nil {
+		item, lockErr = d.lockedItem(item)
+	}
+	storePath := GetItemFilePath(item.FileName, d.outputDir)
+	plan := PlanItem{
+		Group:     task.Group,
+		Module:    item.Module,
+		FileName:  item.FileName,
+		Path:      storePath,
+		KeepOld:   d.keepOld,
+		LocalSize: -1,
+		Release:   item.GitHubRelease,
+	}
+	if lockErr != nil {
+		plan.Error = lockErr.Error()
+	}
+
+	info, err := os.Stat(storePath)
+	fileExists := err == nil
+	if fileExists {
+		plan.LocalSize = info.Size()
+	}
+	switch {
+	case !fileExists:
+		plan.Action, plan.Reason = PlanDownload, ReasonMissing
+	case d.forceUpdate:
+		plan.Action, plan.Reason = PlanReplace, ReasonForce
+	case d.lock != nil && d.needsUpdate(item, storePath, fileExists):
+		plan.Action, plan.Reason = PlanReplace, ReasonLockMismatch
+	case d.lock != nil:
+		plan.Action, plan.Reason = PlanSkip, ReasonLocked
+	case d.needsUpdate(item, storePath, fileExists):
+		plan.Action, plan.Reason = PlanReplace, ReasonKeepUpdated
+	case item.KeepUpdated:
+		plan.Action, plan.Reason = PlanSkip, ReasonCached
+	default:
+		plan.Action, plan.Reason = PlanSkip, ReasonExists
+	}
+
+	if probe {
+		resolved, release, err := d.resolveItemRelease(ctx, item, log)
+		if err != nil {
+			plan.Error = err.Error()
+		}
+		item = resolved
+		plan.Tag = release.Tag
+		if plan.Action == PlanReplace && d.releaseUnchanged(storePath, release) {
+			plan.Action, plan.Reason = PlanSkip, ReasonReleaseUnchanged
+		}
+	}
+	plan.URLs = d.itemMirrors(item, log)
+	if len(plan.URLs) == 0 && item.GitHubRelease == "" && plan.Error == "" {
+		plan.Error = Msg("item.no_urls")
+	}
+	if probe {
+		plan.Mirrors = probeMirrors(ctx, d.client, plan.URLs)
+	}
+	return plan
+}
+
+// probeMirrors 并发探测所有下载源，不记录下载源统计
+func probeMirrors(ctx context.Context, client *http.Client, urls []string) []PlanMirror {
+	mirrors := make([]PlanMirror, len(urls))
+	var wg sync.WaitGroup
+	for i, downloadURL := range urls {
+		wg.Add(1)
+		go func(i int, downloadURL string) {
+			defer wg.Done()
+			probe := probeMirror(ctx, client, downloadURL)
+			mirrors[i] = PlanMirror{URL: probe.URL, Status: probe.StatusCode, Size: probe.Size}
+			if probe.Err != nil {
+				mirrors[i].Error = probe.Err.Error()
+			}
+		}(i, downloadURL)
+	}
+	wg.Wait()
+	return mirrors
+}
+
+// PlanCounts 统计下载计划中各处理方式的下载项数量
+func PlanCounts(plans []PlanItem) (download, replace, skip int) {
+	for _, plan := range plans {
+		switch plan.Action {
+		case PlanDownload:
+			download++
+		case PlanReplace:
+			replace++
+		default:
+			skip++
+		}
+	}
+	return download, replace, skip
+}
+
+// jsonPlan JSON格式下载计划
+type jsonPlan struct {
+	Total    int        `json:"total"`
+	Download int        `json:"download"`
+	Replace  int        `json:"replace"`
+	Skip     int        `json:"skip"`
+	Items    []PlanItem `json:"items"`
+}
+
+// WritePlanJSON 将下载计划以JSON格式写入 w
+func WritePlanJSON(w io.Writer, plans []PlanItem) error {
+	plan := jsonPlan{Total: len(plans), Items: plans}
+	if plan.Items == nil {
+		plan.Items = []PlanItem{}
+	}
+	plan.Download, plan.Replace, plan.Skip = PlanCounts(plans)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
@@ -0,0 +1,32 @@
+package downfile
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPlanItemWithoutURLs(t *testing.T) {
+	tasks := []DownTask{
+		{Group: "g", Item: DownItem{Module: "empty", FileName: "empty.bin"}},
+		{Group: "g", Item: DownItem{Module: "url", FileName: "url.bin", DownloadURLs: []string{"https://example.com/url.bin"}}},
+		{Group: "g", Item: DownItem{Module: "release", FileName: "release.bin", GitHubRelease: "owner/repo", Asset: "*.bin"}},
+	}
+	plans := testDownloader(t).Plan(context.Background(), tasks, false)
+
+	tests := []struct {
+		module string
+		wantOK bool
+	}{
+		{module: "empty", wantOK: false},
+		{module: "url", wantOK: true},
+		{module: "release", wantOK: true},
+	}
+	for i, tt := range tests {
+		if plans[i].Module != tt.module || plans[i].OK() != tt.wantOK {
+			t.Errorf("plan %s: OK() = %v, want %v (error %q)", plans[i].Module, plans[i].OK(), tt.wantOK, plans[i].Error)
+		}
+	}
+	if plans[0].Error != Msg("item.no_urls") {
+		t.Errorf("error = %q, want no urls message", plans[0].Error)
+	}
+}
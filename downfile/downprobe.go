@@ -0,0 +1,186 @@
+package downfile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MirrorProbe 单个下载源的探测结果
+type MirrorProbe struct {
+	URL        string
+	StatusCode int
+	Size       int64 // 文件大小，未知时为-1
+	Latency    time.Duration
+	Err        error
+}
+
+// Reachable 判断下载源是否可用
+func (p MirrorProbe) Reachable() bool {
+	return p.Err == nil
+}
+
+// ProbeResult 下载项的探测结果
+type ProbeResult struct {
+	Group   string
+	Module  string
+	Mirrors []MirrorProbe
+}
+
+// Reachable 判断下载项是否至少有一个可用的下载源
+func (r ProbeResult) Reachable() bool {
+	for _, mirror := range r.Mirrors {
+		if mirror.Reachable() {
+			return true
+		}
+	}
+	return false
+}
+
+// SizeConsistent 判断所有可用且返回了文件大小的下载源大小是否一致
+func (r ProbeResult) SizeConsistent() bool {
+	size := int64(-1)
+	for _, mirror := range r.Mirrors {
+		if !mirror.Reachable() || mirror.Size < 0 {
+			continue
+		}
+		if size >= 0 && mirror.Size != size {
+			return false
+		}
+		size = mirror.Size
+	}
+	return true
+}
+
+// OK 判断下载项的探测结果是否正常（有可用下载源且文件大小一致）
+func (r ProbeResult) OK() bool {
+	return r.Reachable() && r.SizeConsistent()
+}
+
+// Probe 使用HEAD请求探测每个下载项的所有下载源，输出可用性及文件大小是否一致，不下载文件
+// 探测结果同样计入下载源的历史统计
+func (d *Downloader) Probe(ctx context.Context, tasks []DownTask) []ProbeResult {
+	results := make([]ProbeResult, len(tasks))
+	currentGroup := ""
+	for i, task := range tasks {
+		if task.Group != "" && task.Group != currentGroup {
+			currentGroup = task.Group
+			d.log("").Infof("group.start", currentGroup)
+		}
+		results[i] = d.probeItem(ctx, task, d.log(""))
+	}
+	return results
+}
+
+// probeItem 并发探测下载项的所有下载源
+func (d *Downloader) probeItem(ctx context.Context, task DownTask, log MsgLogger) ProbeResult {
+	item, _, err := d.resolveItemRelease(ctx, task.Item, log)
+	if err != nil {
+		log.Errorf("item.error", err)
+	}
+	urls := d.itemMirrors(DownItem{DownloadURLs: item.DownloadURLs, MirrorStrategy: MirrorOrdered}, log)
+	result := ProbeResult{Group: task.Group, Module: task.Item.Module, Mirrors: make([]MirrorProbe, len(urls))}
+	log.Infof("probe.item", task.Item.Module, len(urls))
+
+	var wg sync.WaitGroup
+	for i, downloadURL := range urls {
+		wg.Add(1)
+		go func(i int, downloadURL string) {
+			defer wg.Done()
+			result.Mirrors[i] = probeMirror(ctx, d.client, downloadURL)
+		}(i, downloadURL)
+	}
+	wg.Wait()
+
+	for _, mirror := range result.Mirrors {
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			if err := d.cache.RecordHost(mirror.URL, mirror.Reachable(), mirror.Latency, 0); err != nil {
+				log.Warnf("mirror.record_failed", err)
+			}
+		}
+		if !mirror.Reachable() {
+			log.Warnf("probe.failed", mirror.URL, mirror.Err)
+			continue
+		}
+		size := Msg("probe.size_unknown")
+		if mirror.Size >= 0 {
+			size = FormatSize(mirror.Size)
+		}
+		log.Infof("probe.ok", mirror.URL, mirror.StatusCode, size, mirror.Latency.Round(time.Millisecond))
+	}
+
+	if !result.Reachable() {
+		log.Errorf("probe.none_reachable", task.Item.Module)
+	} else if !result.SizeConsistent() {
+		log.Warnf("probe.size_mismatch", task.Item.Module)
+	}
+	return result
+}
+
+// probeMirror 探测单个下载源，服务器不支持HEAD请求时改用只请求第一个字节的GET请求
+func probeMirror(ctx context.Context, client *http.Client, downloadUrl string) MirrorProbe {
+	probe := MirrorProbe{URL: downloadUrl, Size: -1}
+	startTime := time.Now()
+	resp, err := probeRequest(ctx, client, http.MethodHead, downloadUrl)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		resp.Body.Close()
+		startTime = time.Now()
+		resp, err = probeRequest(ctx, client, http.MethodGet, downloadUrl)
+	}
+	if err != nil {
+		probe.Err = msgError("http.request_failed", err)
+		return probe
+	}
+	resp.Body.Close()
+	probe.Latency = time.Since(startTime)
+	probe.StatusCode = resp.StatusCode
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		probe.Size = resp.ContentLength
+	case http.StatusPartialContent:
+		probe.Size = contentRangeSize(resp.Header.Get("Content-Range"))
+	case http.StatusNotFound:
+		probe.Err = DownloadError{
+			StatusCode: resp.StatusCode,
+			Message:    Msg("http.not_found", resp.StatusCode),
+			Type:       ErrResourceNotFound,
+		}
+	default:
+		probe.Err = DownloadError{
+			StatusCode: resp.StatusCode,
+			Message:    Msg("http.status_failed", resp.StatusCode),
+		}
+	}
+	return probe
+}
+
+// probeRequest 发送探测请求，GET请求只请求第一个字节
+func probeRequest(ctx context.Context, client *http.Client, method, downloadUrl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, downloadUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	return client.Do(req)
+}
+
+// contentRangeSize 从 Content-Range 响应头（如 bytes 0-0/1234）中获取文件总大小，未知时返回-1
+func contentRangeSize(contentRange string) int64 {
+	index := strings.LastIndex(contentRange, "/")
+	if index < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[index+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
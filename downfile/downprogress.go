@@ -0,0 +1,244 @@
+package downfile
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// 进度输出模式
+const (
+	ProgressAuto  = "auto"  // 输出为终端时使用 tty，否则使用 plain
+	ProgressTTY   = "tty"   // 终端进度条
+	ProgressPlain = "plain" // 按行输出进度日志
+	ProgressJSON  = "json"  // JSON Lines 事件
+	ProgressNone  = "none"  // 不输出进度
+)
+
+// PlainProgressInterval plain 模式下输出进度日志的间隔
+var PlainProgressInterval = 5 * time.Second
+
+// ProgressEvent 下载进度事件
+type ProgressEvent struct {
+	ID         uint64        // 下载标识，同一次下载的所有事件相同
+	Name       string        // 下载的文件名
+	Downloaded int64         // 已下载字节数（包括续传前已下载的部分）
+	Total      int64         // 文件总大小，未知时小于等于0
+	Offset     int64         // 续传起始位置
+	Speed      float64       // 当前下载速度 (bytes/second)
+	Elapsed    time.Duration // 已用时间
+	Err        error         // 下载中断原因（仅 Error 事件）
+	Segments   []int64       // 分段下载时每个分段已下载的字节数，不是分段下载时为nil
+}
+
+// ProgressReporter 下载进度报告接口
+// Start 在开始传输时调用，Progress 定期调用，Done 与 Error 在传输结束时二选一调用一次
+type ProgressReporter interface {
+	Start(event ProgressEvent)
+	Progress(event ProgressEvent)
+	Done(event ProgressEvent)
+	Error(event ProgressEvent)
+}
+
+// ttyReporter 终端进度条，由终端输出协调器统一绘制进度行
+type ttyReporter struct {
+	board *progressBoard
+}
+
+func (r ttyReporter) Start(event ProgressEvent)    { r.board.add(event) }
+func (r ttyReporter) Progress(event ProgressEvent) { r.board.update(event) }
+func (r ttyReporter) Done(event ProgressEvent)     { r.board.remove(event.ID) }
+func (r ttyReporter) Error(event ProgressEvent)    { r.board.remove(event.ID) }
+
+// plainReporter 按行输出进度日志，适用于日志文件及CI输出
+type plainReporter struct {
+	logger   MsgLogger
+	interval time.Duration
+	mu       sync.Mutex
+	lastLog  map[uint64]time.Time
+}
+
+// newPlainReporter 创建按行输出的进度报告器
+func newPlainReporter(logger MsgLogger) *plainReporter {
+	return &plainReporter{logger: logger, interval: PlainProgressInterval, lastLog: make(map[uint64]time.Time)}
+}
+
+func (r *plainReporter) Start(event ProgressEvent) {
+	r.mu.Lock()
+	r.lastLog[event.ID] = time.Now()
+	r.mu.Unlock()
+	if event.Total > 0 {
+		r.logger.Infof("progress.start_size", event.Name, FormatSize(event.Total))
+	} else {
+		r.logger.Infof("progress.start_unknown", event.Name)
+	}
+}
+
+func (r *plainReporter) Progress(event ProgressEvent) {
+	r.mu.Lock()
+	if time.Since(r.lastLog[event.ID]) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastLog[event.ID] = time.Now()
+	r.mu.Unlock()
+	r.logger.Infof("progress.item_line", event.Name, formatProgressLine(event))
+}
+
+func (r *plainReporter) Done(event ProgressEvent) {
+	r.forget(event.ID)
+}
+
+func (r *plainReporter) Error(event ProgressEvent) {
+	r.forget(event.ID)
+}
+
+// forget 删除已结束下载的记录
+func (r *plainReporter) forget(id uint64) {
+	r.mu.Lock()
+	delete(r.lastLog, id)
+	r.mu.Unlock()
+}
+
+// jsonReporter 输出 JSON Lines 格式的进度事件
+type jsonReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// jsonProgressEvent JSON Lines 进度事件
+type jsonProgressEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"` // start|progress|done|error
+	ID         uint64    `json:"id"`
+	Name       string    `json:"name"`
+	Downloaded int64     `json:"downloaded"`
+	Total      int64     `json:"total"`
+	Speed      float64   `json:"speed"`
+	Elapsed    float64   `json:"elapsed_seconds"`
+	Error      string    `json:"error,omitempty"`
+	Segments   []int64   `json:"segments,omitempty"`
+}
+
+// NewJSONProgressReporter 创建将进度事件以 JSON Lines 格式写入 w 的进度报告器
+func NewJSONProgressReporter(w io.Writer) ProgressReporter {
+	return &jsonReporter{w: w}
+}
+
+func (r *jsonReporter) Start(event ProgressEvent)    { r.write("start", event) }
+func (r *jsonReporter) Progress(event ProgressEvent) { r.write("progress", event) }
+func (r *jsonReporter) Done(event ProgressEvent)     { r.write("done", event) }
+func (r *jsonReporter) Error(event ProgressEvent)    { r.write("error", event) }
+
+// write 写入一行事件
+func (r *jsonReporter) write(kind string, event ProgressEvent) {
+	line := jsonProgressEvent{
+		Time:       time.Now(),
+		Event:      kind,
+		ID:         event.ID,
+		Name:       event.Name,
+		Downloaded: event.Downloaded,
+		Total:      event.Total,
+		Speed:      event.Speed,
+		Elapsed:    event.Elapsed.Seconds(),
+		Segments:   event.Segments,
+	}
+	if event.Err != nil {
+		line.Error = event.Err.Error()
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.w.Write(append(data, '\n'))
+}
+
+// silentReporter 不输出任何进度
+type silentReporter struct{}
+
+func (silentReporter) Start(ProgressEvent)    {}
+func (silentReporter) Progress(ProgressEvent) {}
+func (silentReporter) Done(ProgressEvent)     {}
+func (silentReporter) Error(ProgressEvent)    {}
+
+// SilentProgressReporter 不输出任何进度的进度报告器
+var SilentProgressReporter ProgressReporter = silentReporter{}
+
+// newModeReporter 根据进度输出模式创建进度报告器，进度行绘制在 board 上，日志输出到 logger
+func newModeReporter(mode string, board *progressBoard, logger MsgLogger) (ProgressReporter, error) {
+	switch mode {
+	case ProgressAuto, "":
+		if board.terminal {
+			return ttyReporter{board: board}, nil
+		}
+		return newPlainReporter(logger), nil
+	case ProgressTTY:
+		return ttyReporter{board: board}, nil
+	case ProgressPlain:
+		return newPlainReporter(logger), nil
+	case ProgressJSON:
+		return NewJSONProgressReporter(board.out), nil
+	case ProgressNone:
+		return SilentProgressReporter, nil
+	}
+	return nil, msgError("progress.unsupported_mode", mode)
+}
+
+// formatProgressLine 生成进度行文本
+func formatProgressLine(event ProgressEvent) string {
+	if event.Total > 0 {
+		return knownSizeProgressLine(event)
+	}
+	return unknownSizeProgressLine(event)
+}
+
+// knownSizeProgressLine 生成已知文件大小的下载进度文本
+func knownSizeProgressLine(event ProgressEvent) string {
+	currentSize := event.Downloaded
+	speed := event.Speed
+	progress := float64(currentSize) / float64(event.Total) * 100
+	speedStr := FormatSize(int64(speed)) + "/s"
+
+	if speed > MinValidSpeed {
+		// 只有当速度大于最小有效值时才计算剩余时间
+		remainingBytes := event.Total - currentSize
+		remainingSeconds := float64(remainingBytes) / speed
+		// 限制最大预估时间为24小时，避免不合理的估计
+		if remainingSeconds > 86400 { // 24小时 = 86400秒
+			remainingSeconds = 86400
+		}
+		remainingTime := time.Duration(remainingSeconds) * time.Second
+
+		return Msg("progress.known_eta",
+			progress,
+			FormatSize(currentSize),
+			FormatSize(event.Total),
+			speedStr,
+			FormatDuration(remainingTime))
+	} else if speed > 0 {
+		// 速度极低但不为0，显示速度但不显示剩余时间
+		return Msg("progress.known_no_eta",
+			progress,
+			FormatSize(currentSize),
+			FormatSize(event.Total),
+			speedStr)
+	}
+	// 速度为0，等待恢复
+	return Msg("progress.known_waiting",
+		progress,
+		FormatSize(currentSize),
+		FormatSize(event.Total))
+}
+
+// unknownSizeProgressLine 生成未知文件大小的下载进度文本
+func unknownSizeProgressLine(event ProgressEvent) string {
+	if event.Speed > MinValidSpeed {
+		return Msg("progress.unknown_speed",
+			FormatSize(event.Downloaded),
+			FormatSize(int64(event.Speed))+"/s")
+	}
+	return Msg("progress.unknown_waiting", FormatSize(event.Downloaded))
+}
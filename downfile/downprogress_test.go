@@ -0,0 +1,137 @@
+package downfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineLogger 记录输出的日志行
+type lineLogger struct {
+	lines []string
+}
+
+func (l *lineLogger) Printf(format string, args ...any) {
+	l.lines = append(l.lines, strings.TrimSuffix(fmt.Sprintf(format, args...), "\n"))
+}
+
+func TestNewModeReporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		terminal bool
+		want     string
+	}{
+		{name: "终端自动选择tty", mode: ProgressAuto, terminal: true, want: "downfile.ttyReporter"},
+		{name: "非终端自动选择plain", mode: ProgressAuto, want: "*downfile.plainReporter"},
+		{name: "未设置模式", mode: "", want: "*downfile.plainReporter"},
+		{name: "tty", mode: ProgressTTY, want: "downfile.ttyReporter"},
+		{name: "plain", mode: ProgressPlain, terminal: true, want: "*downfile.plainReporter"},
+		{name: "json", mode: ProgressJSON, want: "*downfile.jsonReporter"},
+		{name: "none", mode: ProgressNone, want: "downfile.silentReporter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, _ := testBoard(tt.terminal, false)
+			reporter, err := newModeReporter(tt.mode, board, MsgLogger{Out: discardLogger{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", reporter); got != tt.want {
+				t.Errorf("reporter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	board, _ := testBoard(false, false)
+	if _, err := newModeReporter("fancy", board, MsgLogger{Out: discardLogger{}}); err == nil {
+		t.Error("newModeReporter() accepted an unknown mode")
+	}
+}
+
+func TestTTYReporter(t *testing.T) {
+	board, buf := testBoard(true, false)
+	reporter := ttyReporter{board: board}
+	event := ProgressEvent{ID: 1, Name: "a.bin", Downloaded: 50, Total: 100}
+
+	reporter.Start(event)
+	reporter.Progress(event)
+	if got := buf.String(); !strings.Contains(got, formatProgressLine(event)) {
+		t.Errorf("output = %q, want the progress line", got)
+	}
+	reporter.Done(event)
+	if len(board.entries) != 0 || board.lines != 0 {
+		t.Errorf("entries = %d, lines = %d after Done, want 0", len(board.entries), board.lines)
+	}
+
+	reporter.Start(ProgressEvent{ID: 2, Name: "b.bin"})
+	reporter.Error(ProgressEvent{ID: 2, Name: "b.bin", Err: errors.New("failed")})
+	if len(board.entries) != 0 {
+		t.Errorf("entries = %d after Error, want 0", len(board.entries))
+	}
+}
+
+func TestPlainReporter(t *testing.T) {
+	logger := &lineLogger{}
+	reporter := newPlainReporter(MsgLogger{Out: logger})
+	reporter.interval = time.Hour
+	event := ProgressEvent{ID: 1, Name: "a.bin", Downloaded: 50, Total: 100}
+
+	reporter.Start(event)
+	reporter.Start(ProgressEvent{ID: 2, Name: "b.bin"})
+	// 未到输出间隔的进度不输出
+	reporter.Progress(event)
+	want := []string{
+		Msg("progress.start_size", "a.bin", FormatSize(100)),
+		Msg("progress.start_unknown", "b.bin"),
+	}
+	if !reflect.DeepEqual(logger.lines, want) {
+		t.Fatalf("lines = %q, want %q", logger.lines, want)
+	}
+
+	reporter.interval = 0
+	reporter.Progress(event)
+	if got, want := logger.lines[len(logger.lines)-1], Msg("progress.item_line", "a.bin", formatProgressLine(event)); got != want {
+		t.Errorf("progress line = %q, want %q", got, want)
+	}
+
+	reporter.Done(event)
+	reporter.Error(ProgressEvent{ID: 2})
+	if len(reporter.lastLog) != 0 {
+		t.Errorf("lastLog = %v after Done/Error, want empty", reporter.lastLog)
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := NewJSONProgressReporter(&buf)
+	event := ProgressEvent{ID: 7, Name: "a.bin", Downloaded: 50, Total: 100, Speed: 10, Elapsed: 2 * time.Second, Segments: []int64{25, 25}}
+	reporter.Start(event)
+	reporter.Progress(event)
+	reporter.Done(event)
+	event.Err = errors.New("failed")
+	reporter.Error(event)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	wantEvents := []string{"start", "progress", "done", "error"}
+	if len(lines) != len(wantEvents) {
+		t.Fatalf("lines = %q, want %d events", lines, len(wantEvents))
+	}
+	for i, line := range lines {
+		var got jsonProgressEvent
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d = %q: %v", i, line, err)
+		}
+		if got.Event != wantEvents[i] || got.ID != 7 || got.Name != "a.bin" || got.Downloaded != 50 || got.Total != 100 || got.Elapsed != 2 || !reflect.DeepEqual(got.Segments, []int64{25, 25}) {
+			t.Errorf("event %d = %+v", i, got)
+		}
+		if wantErr := wantEvents[i] == "error"; (got.Error == "failed") != wantErr {
+			t.Errorf("event %d error = %q", i, got.Error)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package downfile
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// raceResult 竞速请求的结果
+type raceResult struct {
+	index int
+	probe MirrorProbe
+}
+
+// raceMirrors 并发向所有下载源发送探测请求（HEAD或只请求第一个字节），最先成功响应的下载源排在最前面，
+// 选出后取消其余请求。探测失败的下载源排在最后，资源不存在 (404) 的下载源不会使下载项判定为不存在，
+// 只有其余下载源都失败后才会尝试它们
+func raceMirrors(ctx context.Context, client *http.Client, urls []string, log MsgLogger) []string {
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 使用带缓冲的通道，取消后剩余的请求可以直接退出
+	results := make(chan raceResult, len(urls))
+	startTime := time.Now()
+	for i, downloadURL := range urls {
+		go func(i int, downloadURL string) {
+			results <- raceResult{index: i, probe: probeMirror(raceCtx, client, downloadURL)}
+		}(i, downloadURL)
+	}
+
+	winner := -1
+	failed := make(map[int]bool)
+	for range urls {
+		result := <-results
+		if result.probe.Reachable() {
+			winner = result.index
+			break
+		}
+		failed[result.index] = true
+		var downloadErr DownloadError
+		if errors.As(result.probe.Err, &downloadErr) && downloadErr.Type == ErrResourceNotFound {
+			log.Infof("race.loser_not_found", result.probe.URL)
+		} else {
+			log.Infof("race.loser_failed", result.probe.URL, result.probe.Err)
+		}
+	}
+	cancel()
+
+	if winner < 0 {
+		log.Warnf("race.no_winner")
+		return urls
+	}
+	log.Infof("race.winner", urls[winner], time.Since(startTime).Round(time.Millisecond))
+
+	// 胜出者优先，其余未失败的下载源保持原有顺序，探测失败的排在最后
+	ordered := make([]string, 0, len(urls))
+	ordered = append(ordered, urls[winner])
+	for i, downloadURL := range urls {
+		if i != winner && !failed[i] {
+			ordered = append(ordered, downloadURL)
+		}
+	}
+	for i, downloadURL := range urls {
+		if failed[i] {
+			ordered = append(ordered, downloadURL)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,178 @@
+package downfile
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultGitHubAPI 默认的 GitHub API 地址，可通过 WithGitHubAPI 替换为兼容的服务（如 GitHub Enterprise 或测试服务器）
+const DefaultGitHubAPI = "https://api.github.com"
+
+// GitHubTokenEnv 读取 GitHub 访问令牌的环境变量，设置后请求携带令牌以提高API速率限制
+var GitHubTokenEnv = "GITHUB_TOKEN"
+
+// MaxGitHubResponseSize GitHub API 响应的最大大小，Release 列表包含大量文件时可能较大
+var MaxGitHubResponseSize int64 = 16 * 1024 * 1024 // 16MB
+
+// ReleaseAsset GitHub Release 中解析出的下载文件
+type ReleaseAsset struct {
+	Tag  string // Release 标签
+	Name string // 文件名
+	URL  string // 下载地址
+	Size int64  // 文件大小
+}
+
+// githubRelease GitHub Releases API 返回的 Release
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+		Size int64  `json:"size"`
+	} `json:"assets"`
+}
+
+// ResolveGitHubRelease 通过 GitHub Releases API 获取下载项匹配的 Release 文件
+// 未配置 tag 且不包含预发布版本时使用最新的 Release，tag 包含通配符时按发布顺序选择第一个匹配的 Release
+func (d *Downloader) ResolveGitHubRelease(ctx context.Context, item DownItem) (ReleaseAsset, error) {
+	repo := strings.Trim(item.GitHubRelease, "/")
+	if strings.Count(repo, "/") != 1 {
+		return ReleaseAsset{}, releaseError("release.invalid_repo", item.GitHubRelease)
+	}
+	if item.Asset == "" {
+		return ReleaseAsset{}, releaseError("release.asset_required", item.GitHubRelease)
+	}
+
+	var releases []githubRelease
+	switch {
+	case item.Tag != "" && !hasWildcard(item.Tag):
+		var release githubRelease
+		if err := d.githubGet(ctx, "/repos/"+repo+"/releases/tags/"+url.PathEscape(item.Tag), &release); err != nil {
+			return ReleaseAsset{}, err
+		}
+		releases = append(releases, release)
+	case item.Tag == "" && !item.Prerelease:
+		var release githubRelease
+		if err := d.githubGet(ctx, "/repos/"+repo+"/releases/latest", &release); err != nil {
+			return ReleaseAsset{}, err
+		}
+		releases = append(releases, release)
+	default:
+		if err := d.githubGet(ctx, "/repos/"+repo+"/releases?per_page=100", &releases); err != nil {
+			return ReleaseAsset{}, err
+		}
+	}
+
+	for _, release := range releases {
+		if release.Draft || (release.Prerelease && !item.Prerelease) {
+			continue
+		}
+		if item.Tag != "" {
+			if matched, _ := path.Match(item.Tag, release.TagName); !matched {
+				continue
+			}
+		}
+		for _, asset := range release.Assets {
+			if matched, _ := path.Match(item.Asset, asset.Name); matched {
+				return ReleaseAsset{Tag: release.TagName, Name: asset.Name, URL: asset.URL, Size: asset.Size}, nil
+			}
+		}
+		// 指定了标签时只查找该 Release
+		if item.Tag != "" && !hasWildcard(item.Tag) {
+			break
+		}
+	}
+	return ReleaseAsset{}, releaseError("release.asset_not_found", item.GitHubRelease, item.Asset)
+}
+
+// githubGet 请求 GitHub API 并解析JSON响应
+func (d *Downloader) githubGet(ctx context.Context, apiPath string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(d.githubAPI, "/")+apiPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", UserAgent)
+	if token := os.Getenv(GitHubTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return releaseError("release.request_failed", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return releaseError("release.not_found", apiPath)
+	case resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0",
+		resp.StatusCode == http.StatusTooManyRequests:
+		return releaseError("release.rate_limited", GitHubTokenEnv)
+	case resp.StatusCode != http.StatusOK:
+		return releaseError("release.status_failed", resp.StatusCode)
+	}
+
+	// 多读取一个字节以判断响应是否超过大小限制，截断的JSON不作为解析错误报告
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxGitHubResponseSize+1))
+	if err != nil {
+		return releaseError("release.request_failed", err)
+	}
+	if int64(len(data)) > MaxGitHubResponseSize {
+		return releaseError("release.response_too_large", MaxGitHubResponseSize, apiPath)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return releaseError("release.parse_failed", err)
+	}
+	return nil
+}
+
+// releaseError 创建无法解析 Release 的下载错误
+func releaseError(key string, args ...any) error {
+	return DownloadError{Message: Msg(key, args...), Type: ErrReleaseUnavailable}
+}
+
+// hasWildcard 判断模式是否包含通配符
+func hasWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// resolveItemRelease 解析下载项的 GitHub Release 文件，将其下载地址放在下载地址列表的最前面
+// 解析失败但配置了其他下载地址时只输出警告
+func (d *Downloader) resolveItemRelease(ctx context.Context, item DownItem, log MsgLogger) (DownItem, ReleaseAsset, error) {
+	if item.GitHubRelease == "" {
+		return item, ReleaseAsset{}, nil
+	}
+	asset, err := d.ResolveGitHubRelease(ctx, item)
+	if err != nil {
+		if len(item.DownloadURLs) == 0 {
+			return item, ReleaseAsset{}, err
+		}
+		log.Warnf("release.fallback", err)
+		return item, ReleaseAsset{}, nil
+	}
+	log.Infof("release.resolved", item.GitHubRelease, asset.Tag, asset.Name)
+	item.DownloadURLs = append([]string{asset.URL}, item.DownloadURLs...)
+	return item, asset, nil
+}
+
+// releaseUnchanged 判断本地文件是否已是解析出的 Release 文件（标签及文件大小与缓存记录一致）
+func (d *Downloader) releaseUnchanged(storePath string, asset ReleaseAsset) bool {
+	if asset.Tag == "" || d.forceUpdate {
+		return false
+	}
+	info, err := os.Stat(storePath)
+	if err != nil {
+		return false
+	}
+	entry, exists := d.cache.GetEntry(storePath)
+	return exists && entry.Tag == asset.Tag && entry.Size == info.Size()
+}
@@ -0,0 +1,269 @@
+package downfile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeRelease 测试用的 Release
+type fakeRelease struct {
+	Tag        string
+	Prerelease bool
+	Draft      bool
+	Assets     []string
+}
+
+// newGitHubAPI 创建模拟 GitHub Releases API 的测试服务器，Release 按发布时间从新到旧排列
+// limited/repo 返回速率限制的403，busy/repo 返回429，token 记录最后一次请求的 Authorization
+func newGitHubAPI(t *testing.T, assetBase string, releases []fakeRelease, token *atomic.Value) *httptest.Server {
+	t.Helper()
+	toJSON := func(release fakeRelease) map[string]any {
+		assets := make([]map[string]any, 0, len(release.Assets))
+		for _, name := range release.Assets {
+			assets = append(assets, map[string]any{
+				"name":                 name,
+				"browser_download_url": assetBase + "/" + release.Tag + "/" + name,
+				"size":                 len(name),
+			})
+		}
+		return map[string]any{"tag_name": release.Tag, "prerelease": release.Prerelease, "draft": release.Draft, "assets": assets}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		// 与 GitHub 一致，latest 不包括预发布版本及草稿
+		for _, release := range releases {
+			if !release.Prerelease && !release.Draft {
+				json.NewEncoder(w).Encode(toJSON(release))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/repos/owner/repo/releases/tags/", func(w http.ResponseWriter, r *http.Request) {
+		tag := r.URL.Path[len("/repos/owner/repo/releases/tags/"):]
+		for _, release := range releases {
+			if release.Tag == tag {
+				json.NewEncoder(w).Encode(toJSON(release))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/repos/owner/repo/releases", func(w http.ResponseWriter, r *http.Request) {
+		list := make([]map[string]any, 0, len(releases))
+		for _, release := range releases {
+			list = append(list, toJSON(release))
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("/repos/limited/repo/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("/repos/busy/repo/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != nil {
+			token.Store(r.Header.Get("Authorization"))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveGitHubRelease(t *testing.T) {
+	releases := []fakeRelease{
+		{Tag: "v3.0.0-rc1", Prerelease: true, Assets: []string{"GeoLite2-City.mmdb"}},
+		{Tag: "v2.1.0", Assets: []string{"GeoLite2-City.mmdb", "GeoLite2-ASN.mmdb", "checksums.txt"}},
+		{Tag: "v2.0.0", Assets: []string{"GeoLite2-City.mmdb"}},
+		{Tag: "v1.0.0", Assets: []string{"city.dat"}},
+		{Tag: "v0.9.0", Draft: true, Assets: []string{"GeoLite2-City.mmdb"}},
+	}
+	const assetBase = "https://downloads.example.com"
+	api := newGitHubAPI(t, assetBase, releases, nil)
+
+	tests := []struct {
+		name      string
+		item      DownItem
+		wantTag   string
+		wantAsset string
+		wantErr   bool
+	}{
+		{
+			name:      "最新Release",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "GeoLite2-City.mmdb"},
+			wantTag:   "v2.1.0",
+			wantAsset: "GeoLite2-City.mmdb",
+		},
+		{
+			name:      "指定标签",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "GeoLite2-City.mmdb", Tag: "v2.0.0"},
+			wantTag:   "v2.0.0",
+			wantAsset: "GeoLite2-City.mmdb",
+		},
+		{
+			name:      "文件名通配符",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "*-ASN.mmdb"},
+			wantTag:   "v2.1.0",
+			wantAsset: "GeoLite2-ASN.mmdb",
+		},
+		{
+			name:      "标签通配符排除预发布版本",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb", Tag: "v*"},
+			wantTag:   "v2.1.0",
+			wantAsset: "GeoLite2-City.mmdb",
+		},
+		{
+			name:      "包含预发布版本",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb", Prerelease: true},
+			wantTag:   "v3.0.0-rc1",
+			wantAsset: "GeoLite2-City.mmdb",
+		},
+		{
+			name:      "跳过没有匹配文件的Release",
+			item:      DownItem{GitHubRelease: "owner/repo", Asset: "*.dat", Tag: "v*"},
+			wantTag:   "v1.0.0",
+			wantAsset: "city.dat",
+		},
+		{
+			name:    "指定标签中没有匹配文件",
+			item:    DownItem{GitHubRelease: "owner/repo", Asset: "*.dat", Tag: "v2.1.0"},
+			wantErr: true,
+		},
+		{
+			name:    "没有匹配文件",
+			item:    DownItem{GitHubRelease: "owner/repo", Asset: "*.zip"},
+			wantErr: true,
+		},
+		{
+			name:    "标签不存在",
+			item:    DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb", Tag: "v9"},
+			wantErr: true,
+		},
+		{
+			name:    "不包括草稿",
+			item:    DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb", Tag: "v0.*"},
+			wantErr: true,
+		},
+		{
+			name:    "速率限制403",
+			item:    DownItem{GitHubRelease: "limited/repo", Asset: "*.mmdb"},
+			wantErr: true,
+		},
+		{
+			name:    "速率限制429",
+			item:    DownItem{GitHubRelease: "busy/repo", Asset: "*.mmdb"},
+			wantErr: true,
+		},
+		{
+			name:    "无效仓库",
+			item:    DownItem{GitHubRelease: "owner", Asset: "*.mmdb"},
+			wantErr: true,
+		},
+		{
+			name:    "未配置文件名模式",
+			item:    DownItem{GitHubRelease: "owner/repo"},
+			wantErr: true,
+		},
+	}
+
+	d := testDownloader(t, WithHTTPClient(api.Client()), WithGitHubAPI(api.URL))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, err := d.ResolveGitHubRelease(context.Background(), tt.item)
+			if tt.wantErr {
+				var downloadErr DownloadError
+				if !errors.As(err, &downloadErr) || downloadErr.Type != ErrReleaseUnavailable {
+					t.Fatalf("err = %v, want %s (asset %+v)", err, ErrReleaseUnavailable, asset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asset.Tag != tt.wantTag || asset.Name != tt.wantAsset {
+				t.Errorf("asset = %s/%s, want %s/%s", asset.Tag, asset.Name, tt.wantTag, tt.wantAsset)
+			}
+			if want := assetBase + "/" + tt.wantTag + "/" + tt.wantAsset; asset.URL != want {
+				t.Errorf("url = %s, want %s", asset.URL, want)
+			}
+		})
+	}
+}
+
+func TestResolveGitHubReleaseRateLimitMessage(t *testing.T) {
+	api := newGitHubAPI(t, "", nil, nil)
+	d := testDownloader(t, WithHTTPClient(api.Client()), WithGitHubAPI(api.URL))
+	_, err := d.ResolveGitHubRelease(context.Background(), DownItem{GitHubRelease: "limited/repo", Asset: "*"})
+	if err == nil || err.Error() != Msg("release.rate_limited", GitHubTokenEnv) {
+		t.Errorf("err = %v, want rate limit message", err)
+	}
+}
+
+func TestResolveGitHubReleaseToken(t *testing.T) {
+	var token atomic.Value
+	api := newGitHubAPI(t, "", []fakeRelease{{Tag: "v1", Assets: []string{"a.mmdb"}}}, &token)
+	d := testDownloader(t, WithHTTPClient(api.Client()), WithGitHubAPI(api.URL))
+
+	t.Setenv(GitHubTokenEnv, "secret")
+	if _, err := d.ResolveGitHubRelease(context.Background(), DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := token.Load(); got != "Bearer secret" {
+		t.Errorf("Authorization = %v, want Bearer token", got)
+	}
+
+	t.Setenv(GitHubTokenEnv, "")
+	if _, err := d.ResolveGitHubRelease(context.Background(), DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := token.Load(); got != "" {
+		t.Errorf("Authorization = %v, want none without token", got)
+	}
+}
+
+func TestProcessItemSkipsUnchangedRelease(t *testing.T) {
+	var downloads atomic.Int32
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Header().Set("ETag", `"asset"`)
+		w.Write([]byte("a.mmdb"))
+	}))
+	defer assets.Close()
+	api := newGitHubAPI(t, assets.URL, []fakeRelease{{Tag: "v1", Assets: []string{"a.mmdb"}}}, nil)
+
+	d := testDownloader(t, WithGitHubAPI(api.URL))
+	item := DownItem{Module: "release", FileName: "a.mmdb", GitHubRelease: "owner/repo", Asset: "*.mmdb", KeepUpdated: true}
+
+	if result := d.DownloadItem(context.Background(), item); result.Status != StatusDownloaded {
+		t.Fatalf("first run status = %s (%s), want %s", result.Status, result.Error, StatusDownloaded)
+	}
+	if result := d.DownloadItem(context.Background(), item); result.Status != StatusNotModified {
+		t.Fatalf("second run status = %s (%s), want %s", result.Status, result.Error, StatusNotModified)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("asset downloaded %d times, want 1", n)
+	}
+}
+
+func TestResolveGitHubReleaseResponseTooLarge(t *testing.T) {
+	api := newGitHubAPI(t, "https://example.com", []fakeRelease{{Tag: "v1", Assets: []string{"a.mmdb", "b.mmdb"}}}, nil)
+	d := testDownloader(t, WithHTTPClient(api.Client()), WithGitHubAPI(api.URL))
+
+	limit := MaxGitHubResponseSize
+	defer func() { MaxGitHubResponseSize = limit }()
+	MaxGitHubResponseSize = 64
+
+	_, err := d.ResolveGitHubRelease(context.Background(), DownItem{GitHubRelease: "owner/repo", Asset: "*.mmdb"})
+	if err == nil || err.Error() != Msg("release.response_too_large", int64(64), "/repos/owner/repo/releases/latest") {
+		t.Errorf("err = %v, want response size error", err)
+	}
+}
@@ -0,0 +1,188 @@
+package downfile
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 运行报告格式
+const (
+	ReportJSON  = "json"
+	ReportJUnit = "junit"
+)
+
+// reportItem JSON报告中的单个下载项
+type reportItem struct {
+	Group    string  `json:"group"`
+	Module   string  `json:"module"`
+	FileName string  `json:"filename"`
+	URL      string  `json:"url,omitempty"`
+	Attempts int     `json:"attempts"`
+	Status   string  `json:"status"`
+	Bytes    int64   `json:"bytes"`
+	Duration float64 `json:"duration_seconds"`
+	AvgSpeed float64 `json:"avg_speed"` // bytes/second
+	Error    string  `json:"error,omitempty"`
+}
+
+// jsonReport JSON格式运行报告
+type jsonReport struct {
+	StartTime time.Time    `json:"start_time"`
+	Duration  float64      `json:"duration_seconds"`
+	Total     int          `json:"total"`
+	Success   int          `json:"success"`
+	Failed    int          `json:"failed"`
+	Items     []reportItem `json:"items"`
+}
+
+// JUnit XML 报告结构，每个配置组对应一个 testsuite，每个下载项对应一个 testcase
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteReport 将下载结果写入运行报告文件，format 为 json 或 junit
+func WriteReport(reportPath, format string, startTime time.Time, results []DownResult) error {
+	var data []byte
+	var err error
+	switch format {
+	case ReportJSON, "":
+		data, err = buildJSONReport(startTime, results)
+	case ReportJUnit:
+		data, err = buildJUnitReport(startTime, results)
+	default:
+		return msgError("report.unsupported_format", format)
+	}
+	if err != nil {
+		return msgError("report.build_failed", err)
+	}
+
+	if err := MakeDirs(reportPath, true); err != nil {
+		return msgError("report.mkdir_failed", err)
+	}
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return msgError("report.write_failed", err)
+	}
+	return nil
+}
+
+// buildJSONReport 生成JSON格式报告
+func buildJSONReport(startTime time.Time, results []DownResult) ([]byte, error) {
+	success := CountSuccess(results)
+	report := jsonReport{
+		StartTime: startTime,
+		Duration:  time.Since(startTime).Seconds(),
+		Total:     len(results),
+		Success:   success,
+		Failed:    len(results) - success,
+		Items:     make([]reportItem, 0, len(results)),
+	}
+	for _, result := range results {
+		report.Items = append(report.Items, reportItem{
+			Group:    result.Group,
+			Module:   result.Module,
+			FileName: result.FileName,
+			URL:      result.URL,
+			Attempts: result.Attempts,
+			Status:   result.Status,
+			Bytes:    result.Bytes,
+			Duration: result.Duration.Seconds(),
+			AvgSpeed: result.AvgSpeed(),
+			Error:    result.Error,
+		})
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// buildJUnitReport 生成JUnit XML格式报告，下载失败及资源不存在的下载项记为 failure
+func buildJUnitReport(startTime time.Time, results []DownResult) ([]byte, error) {
+	suites := junitTestSuites{
+		Name: "downtools",
+		Time: formatSeconds(time.Since(startTime)),
+	}
+	suiteIndex := make(map[string]int)
+
+	for _, result := range results {
+		index, exists := suiteIndex[result.Group]
+		if !exists {
+			index = len(suites.Suites)
+			suiteIndex[result.Group] = index
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: result.Group})
+		}
+		suite := &suites.Suites[index]
+
+		testCase := junitTestCase{
+			Name:      result.Module,
+			ClassName: result.Group,
+			Time:      formatSeconds(result.Duration),
+			SystemOut: fmt.Sprintf("status=%s url=%s attempts=%d bytes=%d avg_speed=%.0f file=%s",
+				result.Status, result.URL, result.Attempts, result.Bytes, result.AvgSpeed(), filepath.ToSlash(result.FileName)),
+		}
+		if !result.Success() {
+			testCase.Failure = &junitFailure{Message: result.Error, Type: result.Status, Text: result.Error}
+			suite.Failures++
+			suites.Failures++
+		} else if result.Status == StatusSkipped {
+			testCase.Skipped = &struct{}{}
+			suite.Skipped++
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		suites.Tests++
+	}
+
+	// 计算每个配置组的总用时
+	for i := range suites.Suites {
+		var total time.Duration
+		for _, result := range results {
+			if result.Group == suites.Suites[i].Name {
+				total += result.Duration
+			}
+		}
+		suites.Suites[i].Time = formatSeconds(total)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// formatSeconds 将时间格式化为JUnit使用的秒数
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,108 @@
+package downfile
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// reportResults 测试用的下载结果，包括成功、跳过、失败及资源不存在
+var reportResults = []DownResult{
+	{Group: "geo", Module: "city", FileName: "city.mmdb", URL: "https://example.com/city.mmdb", Attempts: 2, Status: StatusDownloaded, Bytes: 2048, Duration: 2 * time.Second},
+	{Group: "geo", Module: "asn", FileName: "asn.mmdb", Status: StatusSkipped},
+	{Group: "ipdb", Module: "qqwry", FileName: "qqwry.dat", Attempts: 3, Status: StatusFailed, Duration: time.Second, Error: "connection refused"},
+	{Group: "ipdb", Module: "zxipv6", FileName: "zxipv6.db", Attempts: 1, Status: StatusNotFound, Error: "not found"},
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "reports", "run.json")
+	if err := WriteReport(reportPath, ReportJSON, time.Now(), reportResults); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != 4 || report.Success != 2 || report.Failed != 2 {
+		t.Errorf("total/success/failed = %d/%d/%d, want 4/2/2", report.Total, report.Success, report.Failed)
+	}
+	if len(report.Items) != len(reportResults) {
+		t.Fatalf("items = %d, want %d", len(report.Items), len(reportResults))
+	}
+	city := report.Items[0]
+	if city.Group != "geo" || city.URL != "https://example.com/city.mmdb" || city.Attempts != 2 || city.Bytes != 2048 || city.Duration != 2 || city.AvgSpeed != 1024 {
+		t.Errorf("item = %+v", city)
+	}
+	if qqwry := report.Items[2]; qqwry.Status != StatusFailed || qqwry.Error != "connection refused" {
+		t.Errorf("failed item = %+v", qqwry)
+	}
+}
+
+func TestWriteReportJUnit(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "junit.xml")
+	if err := WriteReport(reportPath, ReportJUnit, time.Now(), reportResults); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+
+	if suites.Tests != 4 || suites.Failures != 2 {
+		t.Errorf("tests/failures = %d/%d, want 4/2", suites.Tests, suites.Failures)
+	}
+	tests := []struct {
+		name     string
+		tests    int
+		failures int
+		skipped  int
+		time     string
+	}{
+		{name: "geo", tests: 2, skipped: 1, time: "2.000"},
+		{name: "ipdb", tests: 2, failures: 2, time: "1.000"},
+	}
+	if len(suites.Suites) != len(tests) {
+		t.Fatalf("suites = %d, want %d", len(suites.Suites), len(tests))
+	}
+	for i, tt := range tests {
+		suite := suites.Suites[i]
+		if suite.Name != tt.name || suite.Tests != tt.tests || suite.Failures != tt.failures || suite.Skipped != tt.skipped || suite.Time != tt.time {
+			t.Errorf("suite %d = %s tests=%d failures=%d skipped=%d time=%s, want %+v",
+				i, suite.Name, suite.Tests, suite.Failures, suite.Skipped, suite.Time, tt)
+		}
+	}
+
+	geo, ipdb := suites.Suites[0], suites.Suites[1]
+	if geo.Cases[0].Failure != nil || geo.Cases[0].Skipped != nil || geo.Cases[1].Skipped == nil {
+		t.Errorf("geo cases = %+v, want a passed and a skipped case", geo.Cases)
+	}
+	failure := ipdb.Cases[0].Failure
+	if failure == nil || failure.Type != StatusFailed || failure.Message != "connection refused" {
+		t.Errorf("failure = %+v, want the failed status and error", failure)
+	}
+	if notFound := ipdb.Cases[1].Failure; notFound == nil || notFound.Type != StatusNotFound {
+		t.Errorf("not-found failure = %+v", notFound)
+	}
+}
+
+func TestWriteReportUnsupportedFormat(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "report.txt")
+	if err := WriteReport(reportPath, "yaml", time.Now(), reportResults); err == nil {
+		t.Error("WriteReport() accepted an unsupported format")
+	}
+	if FileExists(reportPath) {
+		t.Error("report written for an unsupported format")
+	}
+}
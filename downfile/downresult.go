@@ -0,0 +1,54 @@
+package downfile
+
+import "time"
+
+// 下载项处理结果状态
+const (
+	// StatusDownloaded 已下载新文件
+	StatusDownloaded = "downloaded"
+	// StatusSkipped 文件已存在且不需要更新，未发送请求
+	StatusSkipped = "skipped"
+	// StatusNotModified 条件请求确认服务器文件未变化
+	StatusNotModified = "not-modified"
+	// StatusFailed 所有下载源都失败
+	StatusFailed = "failed"
+	// StatusNotFound 下载源返回404
+	StatusNotFound = "404"
+)
+
+// DownResult 单个下载项的处理结果
+type DownResult struct {
+	Group    string        // 所属配置组
+	Module   string        // 模块名称
+	FileName string        // 保存的文件名
+	URL      string        // 最后使用（成功时为实际下载）的下载地址
+	Attempts int           // 请求次数（包括重试及切换下载源）
+	Status   string        // 处理结果状态
+	Bytes    int64         // 实际传输的字节数
+	Duration time.Duration // 处理用时
+	Error    string        // 失败原因
+}
+
+// Success 判断下载项是否处理成功
+func (r DownResult) Success() bool {
+	return r.Status != StatusFailed && r.Status != StatusNotFound
+}
+
+// AvgSpeed 获取平均下载速度 (bytes/second)
+func (r DownResult) AvgSpeed() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// CountSuccess 统计处理成功的下载项数量
+func CountSuccess(results []DownResult) int {
+	count := 0
+	for _, result := range results {
+		if result.Success() {
+			count++
+		}
+	}
+	return count
+}
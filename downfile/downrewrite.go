@@ -0,0 +1,88 @@
+package downfile
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RewriteRule 下载地址改写规则，为匹配的下载地址生成额外的候选下载地址
+// URLs 为替换模板，支持 $1、${name} 等引用正则表达式的分组；Prefixes 为加在原地址前面的代理前缀
+type RewriteRule struct {
+	Match    string   `yaml:"match"`    // 匹配下载地址的正则表达式
+	URLs     []string `yaml:"urls"`     // 候选下载地址模板，如 https://cdn.jsdelivr.net/gh/$1/$2@$3/$4
+	Prefixes []string `yaml:"prefixes"` // 代理前缀，如 https://ghproxy.com/
+	pattern  *regexp.Regexp
+}
+
+// Compile 编译匹配规则，加载配置时调用
+func (r *RewriteRule) Compile() error {
+	if r.Match == "" {
+		return msgError("rewrite.match_required")
+	}
+	if len(r.URLs) == 0 && len(r.Prefixes) == 0 {
+		return msgError("rewrite.target_required", r.Match)
+	}
+	pattern, err := regexp.Compile(r.Match)
+	if err != nil {
+		return msgError("rewrite.invalid_match", r.Match, err)
+	}
+	r.pattern = pattern
+	return nil
+}
+
+// Expand 获取下载地址经过改写生成的候选下载地址，不匹配或规则未编译时返回nil
+// Expand 不修改规则，编译后的规则可以被多个 goroutine 同时使用
+func (r *RewriteRule) Expand(downloadUrl string) []string {
+	if r.pattern == nil {
+		return nil
+	}
+	match := r.pattern.FindStringSubmatchIndex(downloadUrl)
+	if match == nil {
+		return nil
+	}
+	var urls []string
+	for _, template := range r.URLs {
+		urls = append(urls, string(r.pattern.ExpandString(nil, template, downloadUrl, match)))
+	}
+	for _, prefix := range r.Prefixes {
+		urls = append(urls, prefix+downloadUrl)
+	}
+	return urls
+}
+
+// CompileRewriteRules 编译所有改写规则
+func CompileRewriteRules(rules []RewriteRule) error {
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteURLs 在下载地址列表后面追加改写规则生成的候选下载地址，去掉重复的地址
+func rewriteURLs(urls []string, rules []RewriteRule) []string {
+	if len(rules) == 0 {
+		return urls
+	}
+	seen := make(map[string]bool, len(urls))
+	result := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !seen[url] {
+			seen[url] = true
+			result = append(result, url)
+		}
+	}
+	for _, url := range urls {
+		for i := range rules {
+			for _, candidate := range rules[i].Expand(url) {
+				candidate = strings.TrimSpace(candidate)
+				if candidate != "" && !seen[candidate] {
+					seen[candidate] = true
+					result = append(result, candidate)
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package downfile
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRewriteURLs(t *testing.T) {
+	rules := []RewriteRule{
+		{Match: `^https://github\.com/([^/]+)/([^/]+)/raw/([^/]+)/(.+)$`, URLs: []string{"https://cdn.jsdelivr.net/gh/$1/$2@$3/$4"}},
+		{Match: `^https://github\.com/`, Prefixes: []string{"https://ghproxy.com/"}},
+	}
+	if err := CompileRewriteRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	urls := []string{"https://github.com/o/r/raw/main/a.mmdb", "https://example.com/a.mmdb", "https://example.com/a.mmdb"}
+	want := []string{
+		"https://github.com/o/r/raw/main/a.mmdb",
+		"https://example.com/a.mmdb",
+		"https://cdn.jsdelivr.net/gh/o/r@main/a.mmdb",
+		"https://ghproxy.com/https://github.com/o/r/raw/main/a.mmdb",
+	}
+	if got := rewriteURLs(urls, rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("rewriteURLs() = %v, want %v", got, want)
+	}
+}
+
+func TestNewDownloaderCompilesRewriteRules(t *testing.T) {
+	if _, err := NewDownloader(WithRewriteRules([]RewriteRule{{Match: "(", Prefixes: []string{"https://proxy/"}}})); err == nil {
+		t.Error("NewDownloader() accepted an invalid rewrite rule")
+	}
+
+	// 未编译的规则由 NewDownloader 编译副本，调用方的规则不被修改
+	rules := []RewriteRule{{Match: `^https://example\.com/`, Prefixes: []string{"https://proxy.example.com/"}}}
+	d := testDownloader(t, WithRewriteRules(rules))
+	if rules[0].pattern != nil {
+		t.Error("caller's rewrite rules were modified")
+	}
+	if got := rules[0].Expand("https://example.com/a.bin"); got != nil {
+		t.Errorf("Expand() on an uncompiled rule = %v, want nil", got)
+	}
+
+	// 并发的下载任务共用编译后的规则
+	item := DownItem{Module: "a", DownloadURLs: []string{"https://example.com/a.bin"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			urls := d.itemMirrors(item, d.log(""))
+			if len(urls) != 2 || urls[1] != "https://proxy.example.com/https://example.com/a.bin" {
+				t.Errorf("itemMirrors() = %v", urls)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestProcessItemRewrittenMirrorDoesNotHideOriginal(t *testing.T) {
+	original := statusServer(t, 200, 200)
+	proxy := statusServer(t, 404, 404)
+	rules := []RewriteRule{{Match: "^" + regexp.QuoteMeta(original.URL) + "(/.*)$", URLs: []string{proxy.URL + "$1"}}}
+	d := testDownloader(t, WithRewriteRules(rules), WithMirrorStrategy(MirrorFastest))
+	// 改写生成的下载源历史速度最快，排在原下载地址前面
+	if err := d.cache.RecordHost(proxy.URL+"/a.bin", true, time.Millisecond, 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	item := DownItem{Module: "a", FileName: "a.bin", DownloadURLs: []string{original.URL + "/a.bin"}}
+	if urls := d.itemMirrors(item, d.log("")); len(urls) != 2 || urls[0] != proxy.URL+"/a.bin" {
+		t.Fatalf("itemMirrors() = %v, want the rewritten mirror first", urls)
+	}
+
+	result := d.DownloadItem(context.Background(), item)
+	if result.Status != StatusDownloaded || result.URL != original.URL+"/a.bin" {
+		t.Errorf("result = %s from %s, want %s from the original URL", result.Status, result.URL, StatusDownloaded)
+	}
+}
@@ -0,0 +1,107 @@
+package downfile
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// DownTask 下载任务（所属配置组及下载项）
+type DownTask struct {
+	Group string
+	Item  DownItem
+}
+
+// BuildDownTasks 将所有配置组展开为下载任务列表，按配置组名称排序
+// enableAll 为 false 时只保留 enable=true 的项
+func BuildDownTasks(config DownConfig, enableAll bool) []DownTask {
+	var tasks []DownTask
+	for _, groupName := range sortedGroupNames(config) {
+		downItems := config[groupName]
+		if !enableAll {
+			downItems = FilterEnableItems(downItems)
+		}
+		for _, item := range downItems {
+			tasks = append(tasks, DownTask{Group: groupName, Item: item})
+		}
+	}
+	return tasks
+}
+
+// sortedGroupNames 获取按名称排序的配置组名称
+func sortedGroupNames(config DownConfig) []string {
+	groupNames := make([]string, 0, len(config))
+	for groupName := range config {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+	return groupNames
+}
+
+// ProcessDownTasks 使用有界工作池处理下载任务（可跨配置组并发），按任务顺序返回每个下载项的处理结果
+// concurrency 小于等于1时按顺序逐个下载，ctx 结束后剩余的下载项直接判定为失败
+func ProcessDownTasks(ctx context.Context, client *http.Client, tasks []DownTask, downloadDir string, forceUpdate bool, keepOld bool, retries int, concurrency int) []DownResult {
+	// 没有设置改写规则，不会返回错误
+	downloader, _ := NewDownloader(
+		WithHTTPClient(client),
+		WithOutputDir(downloadDir),
+		WithForceUpdate(forceUpdate),
+		WithKeepOld(keepOld),
+		WithRetries(retries),
+		WithConcurrency(concurrency),
+	)
+	return downloader.Download(ctx, tasks)
+}
+
+// Download 使用有界工作池处理下载任务（可跨配置组并发），按任务顺序返回每个下载项的处理结果
+// ctx 结束后剩余的下载项直接判定为失败
+func (d *Downloader) Download(ctx context.Context, tasks []DownTask) []DownResult {
+	manifests := newChecksumManifests()
+	results := make([]DownResult, len(tasks))
+
+	// 顺序下载，保持原有的按组输出方式
+	if d.concurrency <= 1 {
+		currentGroup := ""
+		for i, task := range tasks {
+			if task.Group != "" && task.Group != currentGroup {
+				currentGroup = task.Group
+				d.log("").Infof("group.start", currentGroup)
+			}
+			results[i] = d.processItem(ctx, task.Item, manifests, d.log(""))
+			results[i].Group = task.Group
+		}
+		return results
+	}
+
+	// 并发下载，使用多行进度视图并为日志添加下载项前缀
+	d.board.setMulti(true)
+	defer d.board.setMulti(false)
+
+	var wg sync.WaitGroup
+	taskChan := make(chan int)
+
+	for i := 0; i < d.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range taskChan {
+				task := tasks[i]
+				log := d.log(task.Item.Module)
+				if task.Group != "" {
+					log = d.log(task.Group + "/" + task.Item.Module)
+				}
+				results[i] = d.processItem(ctx, task.Item, manifests, log)
+				results[i].Group = task.Group
+			}
+		}()
+	}
+
+	for i := range tasks {
+		taskChan <- i
+	}
+	close(taskChan)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,124 @@
+package downfile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuildDownTasks(t *testing.T) {
+	config := DownConfig{
+		"b": {{Module: "b1", Enable: true}, {Module: "b2"}},
+		"a": {{Module: "a1", Enable: true}},
+	}
+	tests := []struct {
+		name      string
+		enableAll bool
+		want      []DownTask
+	}{
+		{name: "只保留启用的下载项", want: []DownTask{
+			{Group: "a", Item: DownItem{Module: "a1", Enable: true}},
+			{Group: "b", Item: DownItem{Module: "b1", Enable: true}},
+		}},
+		{name: "包括未启用的下载项", enableAll: true, want: []DownTask{
+			{Group: "a", Item: DownItem{Module: "a1", Enable: true}},
+			{Group: "b", Item: DownItem{Module: "b1", Enable: true}},
+			{Group: "b", Item: DownItem{Module: "b2"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildDownTasks(config, tt.enableAll); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildDownTasks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// concurrencyServer 记录同时处理的最大请求数，每个请求等待 delay 后返回
+func concurrencyServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		w.Write([]byte(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server, &peak
+}
+
+func TestDownloadConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "顺序下载", concurrency: 1},
+		{name: "并发下载", concurrency: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, peak := concurrencyServer(t, 50*time.Millisecond)
+			var tasks []DownTask
+			for i := 0; i < 8; i++ {
+				module := "m" + strconv.Itoa(i)
+				tasks = append(tasks, DownTask{
+					Group: "g" + strconv.Itoa(i%2),
+					Item:  DownItem{Module: module, FileName: module + ".bin", DownloadURLs: []string{server.URL + "/" + module}},
+				})
+			}
+
+			d := testDownloader(t, WithConcurrency(tt.concurrency), WithRetries(1))
+			results := d.Download(context.Background(), tasks)
+
+			// 结果按任务顺序返回，并记录所属配置组
+			if len(results) != len(tasks) {
+				t.Fatalf("results = %d, want %d", len(results), len(tasks))
+			}
+			for i, result := range results {
+				if result.Module != tasks[i].Item.Module || result.Group != tasks[i].Group {
+					t.Errorf("result %d = %s/%s, want %s/%s", i, result.Group, result.Module, tasks[i].Group, tasks[i].Item.Module)
+				}
+				if result.Status != StatusDownloaded {
+					t.Errorf("result %d status = %s, want %s (%s)", i, result.Status, StatusDownloaded, result.Error)
+				}
+			}
+			if got := int(peak.Load()); got > tt.concurrency || (tt.concurrency > 1 && got < 2) {
+				t.Errorf("peak concurrent requests = %d, want between 2 and %d", got, tt.concurrency)
+			}
+		})
+	}
+}
+
+func TestDownloadCancelledContext(t *testing.T) {
+	server, peak := concurrencyServer(t, 0)
+	tasks := []DownTask{
+		{Group: "g", Item: DownItem{Module: "a", FileName: "a.bin", DownloadURLs: []string{server.URL + "/a"}}},
+		{Group: "g", Item: DownItem{Module: "b", FileName: "b.bin", DownloadURLs: []string{server.URL + "/b"}}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// ctx 结束后剩余的下载项直接判定为失败，不再发送请求
+	d := testDownloader(t, WithConcurrency(2))
+	for i, result := range d.Download(ctx, tasks) {
+		if result.Status != StatusFailed || result.Error != Msg("item.run_timeout") {
+			t.Errorf("result %d = %s (%s), want %s", i, result.Status, result.Error, StatusFailed)
+		}
+	}
+	if peak.Load() != 0 {
+		t.Error("requests sent after the context was cancelled")
+	}
+}
@@ -0,0 +1,180 @@
+package downfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// MinSegmentSize 分段下载时每个分段的最小大小，文件较小时减少分段数量
+var MinSegmentSize int64 = 1024 * 1024 // 1MB
+
+// byteRange 分段下载的字节范围（包含 End）
+type byteRange struct {
+	Start int64
+	End   int64
+}
+
+// Length 获取分段长度
+func (r byteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+// segmentCount 根据响应判断实际使用的分段数量，服务器不支持范围请求、文件大小未知或正在续传时返回1
+func segmentCount(segments int, resp *http.Response, offset int64) int {
+	if segments <= 1 || offset > 0 || resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength <= 0 {
+		return 1
+	}
+	if maxSegments := resp.ContentLength / MinSegmentSize; int64(segments) > maxSegments {
+		segments = int(maxSegments)
+	}
+	if segments < 1 {
+		return 1
+	}
+	return segments
+}
+
+// splitRanges 将文件平均分成 segments 个字节范围
+func splitRanges(size int64, segments int) []byteRange {
+	ranges := make([]byteRange, segments)
+	for i := range ranges {
+		ranges[i] = byteRange{
+			Start: size * int64(i) / int64(segments),
+			End:   size*int64(i+1)/int64(segments) - 1,
+		}
+	}
+	return ranges
+}
+
+// downloadSegments 将文件分成多个字节范围并行下载，写入 out 的对应位置，tracker 需要已设置分段数量
+// 第一个分段直接读取 resp 的响应体，其余分段使用范围请求，配置了备用下载源时轮流从各下载源获取
+// 任一分段失败时取消其余分段
+func downloadSegments(ctx context.Context, client *http.Client, request downloadRequest, resp *http.Response, out *os.File, tracker *ProgressTracker, segments int, validator string) error {
+	size := resp.ContentLength
+	if err := out.Truncate(size); err != nil {
+		return msgError("segment.truncate_failed", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sources := segmentSources(ctx, client, request, size)
+	if len(sources) > 1 {
+		request.Logger.Infof("segment.start_mirrors", segments, len(sources))
+	} else {
+		request.Logger.Infof("segment.start", segments)
+	}
+
+	ranges := splitRanges(size, segments)
+	errs := make([]error, segments)
+	var wg sync.WaitGroup
+	for i, segment := range ranges {
+		wg.Add(1)
+		go func(i int, segment byteRange) {
+			defer wg.Done()
+			writer := tracker.SegmentWriter(i, io.NewOffsetWriter(out, segment.Start))
+			if i == 0 {
+				errs[i] = copySegment(writer, resp.Body, segment)
+			} else {
+				source := sources[i%len(sources)]
+				if source == request.URL {
+					errs[i] = fetchSegment(ctx, client, source, segment, validator, writer)
+				} else {
+					// 各下载源的校验标识不同，备用下载源只依靠文件大小保证一致
+					errs[i] = fetchSegment(ctx, client, source, segment, "", writer)
+				}
+				if errs[i] != nil && ctx.Err() == nil && source != request.URL {
+					// 备用下载源失败时从主下载源重新获取该分段
+					request.Logger.Warnf("segment.mirror_failed", source, errs[i])
+					tracker.ResetSegment(i)
+					writer = tracker.SegmentWriter(i, io.NewOffsetWriter(out, segment.Start))
+					errs[i] = fetchSegment(ctx, client, request.URL, segment, validator, writer)
+				}
+			}
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, segment)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segmentSources 获取分段下载使用的下载源，只使用文件大小与主下载源一致的备用下载源
+func segmentSources(ctx context.Context, client *http.Client, request downloadRequest, size int64) []string {
+	sources := []string{request.URL}
+	for _, mirror := range request.Mirrors {
+		probe := probeMirror(ctx, client, mirror)
+		if !probe.Reachable() || probe.Size != size {
+			request.Logger.Debugf("segment.mirror_skipped", mirror)
+			continue
+		}
+		sources = append(sources, mirror)
+	}
+	return sources
+}
+
+// fetchSegment 使用范围请求下载一个分段，validator 不为空时作为 If-Range 保证文件未变化
+func fetchSegment(ctx context.Context, client *http.Client, downloadUrl string, segment byteRange, validator string, w io.Writer) error {
+	header := make(http.Header)
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", segment.Start, segment.End))
+	if validator != "" {
+		header.Set("If-Range", validator)
+	}
+	resp, err := httpGet(ctx, client, downloadUrl, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 服务器返回完整文件（不支持范围请求或文件已变化）时无法分段下载
+	if resp.StatusCode != http.StatusPartialContent {
+		return msgError("segment.range_ignored", downloadUrl)
+	}
+	start, err := parseContentRangeStart(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+	if start != segment.Start {
+		return msgError("partial.offset_mismatch", start, segment.Start)
+	}
+	return copySegment(w, resp.Body, segment)
+}
+
+// copySegment 从 r 中复制一个分段的数据，数据不完整时返回错误
+func copySegment(w io.Writer, r io.Reader, segment byteRange) error {
+	buf := make([]byte, DownloadBufferSize)
+	written, err := copyBuffer(w, io.LimitReader(r, segment.Length()), buf)
+	if err != nil {
+		return msgError("download.copy_failed", err)
+	}
+	if written != segment.Length() {
+		return msgError("segment.incomplete", segment.Start, segment.End, written, segment.Length())
+	}
+	return nil
+}
+
+// hashFile 将文件内容写入 w，用于分段下载完成后计算校验值
+func hashFile(path string, w io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return msgError("partial.open_failed", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(w, file); err != nil {
+		return msgError("partial.read_failed", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package downfile
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// withMinSegmentSize 在测试期间修改分段的最小大小
+func withMinSegmentSize(t *testing.T, size int64) {
+	t.Helper()
+	old := MinSegmentSize
+	MinSegmentSize = size
+	t.Cleanup(func() { MinSegmentSize = old })
+}
+
+func TestSplitRanges(t *testing.T) {
+	tests := []struct {
+		size     int64
+		segments int
+	}{
+		{size: 10, segments: 1},
+		{size: 10, segments: 3},
+		{size: 1000, segments: 4},
+		{size: 1001, segments: 7},
+	}
+	for _, tt := range tests {
+		ranges := splitRanges(tt.size, tt.segments)
+		if len(ranges) != tt.segments {
+			t.Fatalf("splitRanges(%d, %d) returned %d ranges", tt.size, tt.segments, len(ranges))
+		}
+		// 分段首尾相接并覆盖整个文件
+		next := int64(0)
+		for _, r := range ranges {
+			if r.Start != next || r.Length() <= 0 {
+				t.Fatalf("splitRanges(%d, %d) = %+v, not contiguous", tt.size, tt.segments, ranges)
+			}
+			next = r.End + 1
+		}
+		if next != tt.size {
+			t.Errorf("splitRanges(%d, %d) covers %d bytes", tt.size, tt.segments, next)
+		}
+	}
+}
+
+func TestSegmentCount(t *testing.T) {
+	withMinSegmentSize(t, 100)
+	response := func(status int, acceptRanges string, length int64) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: make(http.Header), ContentLength: length}
+		if acceptRanges != "" {
+			resp.Header.Set("Accept-Ranges", acceptRanges)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name     string
+		segments int
+		resp     *http.Response
+		offset   int64
+		want     int
+	}{
+		{"未启用分段", 1, response(200, "bytes", 1000), 0, 1},
+		{"正常分段", 4, response(200, "bytes", 1000), 0, 4},
+		{"文件较小时减少分段", 8, response(200, "bytes", 350), 0, 3},
+		{"不支持范围请求", 4, response(200, "", 1000), 0, 1},
+		{"文件大小未知", 4, response(200, "bytes", -1), 0, 1},
+		{"正在续传", 4, response(206, "bytes", 1000), 500, 1},
+		{"文件小于最小分段", 4, response(200, "bytes", 50), 0, 1},
+	}
+	for _, tt := range tests {
+		if got := segmentCount(tt.segments, tt.resp, tt.offset); got != tt.want {
+			t.Errorf("%s: segmentCount() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadFileSegments(t *testing.T) {
+	withMinSegmentSize(t, 1024)
+	content := []byte(strings.Repeat("segmented-content-", 1000))
+	server := newRangeServer(t, content, `"v1"`)
+	storePath := filepath.Join(t.TempDir(), "file.bin")
+
+	request := testRequest(t, server.URL, storePath)
+	request.Segments = 4
+	request.Checksums = map[string]string{AlgoSHA256: sha256Hex(string(content))}
+	if _, err := downloadFile(context.Background(), server.Client(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(storePath); !bytes.Equal(got, content) {
+		t.Fatalf("assembled file differs from content (%d bytes)", len(got))
+	}
+	ranged := 0
+	for _, r := range server.recorded() {
+		if r.Range != "" {
+			ranged++
+			if r.IfRange != `"v1"` {
+				t.Errorf("segment request %q sent without If-Range", r.Range)
+			}
+		}
+	}
+	if ranged != 3 {
+		t.Errorf("range requests = %d, want 3", ranged)
+	}
+}
+
+func TestDownloadFileSegmentRetriesFromPrimary(t *testing.T) {
+	withMinSegmentSize(t, 1024)
+	content := []byte(strings.Repeat("mirror-retry-", 1000))
+	primary := newRangeServer(t, content, `"v1"`)
+
+	// 备用下载源的文件大小一致，但分段请求失败
+	var mirrorRanges atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			return
+		}
+		mirrorRanges.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+
+	storePath := filepath.Join(t.TempDir(), "file.bin")
+	request := testRequest(t, primary.URL, storePath)
+	request.Segments = 4
+	request.Mirrors = []string{mirror.URL}
+	if _, err := downloadFile(context.Background(), primary.Client(), request); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(storePath); !bytes.Equal(got, content) {
+		t.Fatalf("assembled file differs from content (%d bytes)", len(got))
+	}
+	if mirrorRanges.Load() == 0 {
+		t.Error("mirror was not used for any segment")
+	}
+	ranged := 0
+	for _, r := range primary.recorded() {
+		if r.Range != "" {
+			ranged++
+		}
+	}
+	// 3个分段请求中分配给备用下载源的分段从主下载源重新获取
+	if want := 3; ranged != want {
+		t.Errorf("primary range requests = %d, want %d", ranged, want)
+	}
+}
+
+func TestDownloadFileSegmentRangeIgnored(t *testing.T) {
+	withMinSegmentSize(t, 1024)
+	content := []byte(strings.Repeat("x", 8192))
+	// 首个响应声明支持范围请求，但分段请求返回完整文件
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	storePath := filepath.Join(t.TempDir(), "file.bin")
+	request := testRequest(t, server.URL, storePath)
+	request.Segments = 4
+	if _, err := downloadFile(context.Background(), server.Client(), request); err == nil {
+		t.Fatal("downloadFile() succeeded, want range ignored error")
+	}
+	if FileExists(storePath) || FileExists(storePath+PartialSuffix) {
+		t.Error("incomplete segmented download left behind")
+	}
+}
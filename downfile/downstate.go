@@ -0,0 +1,50 @@
+package downfile
+
+import "path/filepath"
+
+// StateDirName 默认状态目录名，位于输出目录下
+var StateDirName = ".downtools"
+
+// StateFileName 状态目录中的下载缓存文件名
+var StateFileName = "state.json"
+
+// DefaultStateDir 获取输出目录的默认状态目录
+func DefaultStateDir(outputDir string) string {
+	return filepath.Join(outputDir, StateDirName)
+}
+
+// NewStateStore 创建保存在状态目录中的下载缓存，输出目录内的文件使用相对路径记录
+// 每个输出目录使用独立的状态文件，移动输出目录或在容器中挂载到其他路径后缓存记录仍然有效
+func NewStateStore(stateDir, outputDir string, expireHours float64) *FileCache {
+	return &FileCache{
+		Path:        filepath.Join(stateDir, StateFileName),
+		BaseDir:     outputDir,
+		ExpireHours: expireHours,
+	}
+}
+
+// MigrateLegacyCache 将旧版缓存文件（用户主目录下的 .download_cache.json）中基准目录内文件的记录及下载源统计迁移到当前缓存
+// 只在当前缓存文件不存在时迁移，返回迁移的记录数量；旧缓存文件保持不变，其他输出目录仍可迁移各自的记录
+func (c *FileCache) MigrateLegacyCache(legacyPath string) (int, error) {
+	if legacyPath == "" || !FileExists(legacyPath) {
+		return 0, nil
+	}
+	defer c.lock()()
+	if FileExists(c.filePath()) {
+		return 0, nil
+	}
+
+	legacy := (&FileCache{Path: legacyPath, Logger: c.Logger}).Load()
+	cache := &DownloadCache{Files: make(map[string]CacheEntry), Hosts: legacy.Hosts}
+	for path, entry := range legacy.Files {
+		key, err := c.key(path)
+		if err != nil || (c.BaseDir != "" && filepath.IsAbs(key)) {
+			continue
+		}
+		cache.Files[key] = entry
+	}
+	if err := c.Save(cache); err != nil {
+		return 0, err
+	}
+	return len(cache.Files), nil
+}
@@ -0,0 +1,50 @@
+package downfile
+
+import (
+	"os"
+	"time"
+)
+
+// ItemStatus 下载项的本地状态，只读取本地文件及缓存，不访问网络
+type ItemStatus struct {
+	Path        string        // 下载文件路径
+	Exists      bool          // 本地文件是否存在
+	Size        int64         // 本地文件大小
+	ModTime     time.Time     // 本地文件修改时间
+	Cached      bool          // 缓存中是否有记录
+	CacheAge    time.Duration // 距离缓存记录的最后下载（或确认未修改）时间
+	Tag         string        // 缓存记录的 GitHub Release 标签
+	Partial     int64         // 未完成下载文件的大小，没有时为0
+	NeedsUpdate bool          // 下次运行时是否会尝试下载
+}
+
+// ItemStatus 获取下载项的本地状态
+func (d *Downloader) ItemStatus(item DownItem) ItemStatus {
+	storePath := GetItemFilePath(item.FileName, d.outputDir)
+	status := ItemStatus{Path: storePath}
+	if info, err := os.Stat(storePath); err == nil {
+		status.Exists = true
+		status.Size = info.Size()
+		status.ModTime = info.ModTime()
+	}
+	if entry, exists := d.cache.GetEntry(storePath); exists {
+		status.Cached = true
+		status.CacheAge = time.Since(entry.DownloadTime)
+		status.Tag = entry.Tag
+	}
+	partialPath, _ := partialPaths(storePath)
+	if info, err := os.Stat(partialPath); err == nil {
+		status.Partial = info.Size()
+	}
+	status.NeedsUpdate = d.needsUpdate(item, storePath, status.Exists)
+	return status
+}
+
+// needsUpdate 判断下载项是否需要下载：强制更新、本地文件不存在，或 keep-updated 且缓存判断需要更新
+// 使用锁定文件时除强制更新外只根据本地文件的SHA256是否与锁定记录一致判断
+func (d *Downloader) needsUpdate(item DownItem, storePath string, fileExists bool) bool {
+	if d.lock != nil {
+		return d.forceUpdate || !fileExists || !fileMatchesSHA256(storePath, item.SHA256)
+	}
+	return d.forceUpdate || !fileExists || (item.KeepUpdated && d.cache.NeedsUpdate(storePath))
+}
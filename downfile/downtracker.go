@@ -1,26 +1,38 @@
 package downfile
 
 import (
-	"fmt"
 	"io"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 // ProgressTracker 下载进度跟踪器
 type ProgressTracker struct {
-	BytesCount   *atomic.Int64 // 已下载字节数
-	FileSize     int64         // 文件总大小
-	StartTime    time.Time     // 下载开始时间
-	LastUpdate   time.Time     // 上次更新时间
-	LastSize     int64         // 上次记录的大小
-	Speed        float64       // 当前下载速度
-	Done         chan struct{} // 完成信号
-	Name         string        // 下载的文件名
-	Cancel       func()        // 用于取消下载的函数
-	CancelReason atomic.Value  // 取消原因
+	BytesCount   *atomic.Int64    // 已下载字节数
+	FileSize     int64            // 文件总大小
+	StartTime    time.Time        // 下载开始时间
+	LastUpdate   time.Time        // 上次更新时间
+	LastSize     int64            // 上次记录的大小
+	Speed        float64          // 当前下载速度
+	Done         chan struct{}    // 完成信号
+	Name         string           // 下载的文件名
+	Cancel       func()           // 用于取消下载的函数
+	Policy       SpeedPolicy      // 低速检测策略
+	CancelReason atomic.Value     // 取消原因
+	StartOffset  int64            // 续传起始位置
+	Logger       MsgLogger        // 日志输出
+	Reporter     ProgressReporter // 进度报告器
+	segments     []atomic.Int64   // 分段下载时每个分段已下载的字节数
+	id           uint64           // 下载标识
+	mu           sync.Mutex       // 保护速度相关字段
+	reportMu     sync.Mutex       // 保证结束事件之后不再报告进度
+	finished     bool             // 是否已报告结束事件
 }
 
+// trackerID 进度跟踪器标识计数器
+var trackerID atomic.Uint64
+
 // NewProgressTracker 创建新的进度跟踪器
 func NewProgressTracker(fileSize int64, name string) *ProgressTracker {
 	now := time.Now()
@@ -36,6 +48,9 @@ func NewProgressTracker(fileSize int64, name string) *ProgressTracker {
 		Done:       make(chan struct{}),
 		Name:       name,
 		Cancel:     func() {}, // 默认空函数
+		Policy:     ItemSpeedPolicy(DownItem{}),
+		Reporter:   ttyReporter{board: console},
+		id:         trackerID.Add(1),
 	}
 
 	// 初始化取消原因为空字符串
@@ -44,9 +59,73 @@ func NewProgressTracker(fileSize int64, name string) *ProgressTracker {
 	return tracker
 }
 
+// SetStartOffset 设置续传起始位置，已下载部分计入进度但不计入速度
+func (pt *ProgressTracker) SetStartOffset(offset int64) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.StartOffset = offset
+	pt.LastSize = offset
+	pt.BytesCount.Store(offset)
+}
+
 // Close 关闭进度跟踪器
 func (pt *ProgressTracker) Close() {
+	pt.Finish(nil)
+}
+
+// Finish 结束进度跟踪，err 不为nil时报告下载中断，否则报告下载完成
+func (pt *ProgressTracker) Finish(err error) {
 	close(pt.Done)
+	if err != nil {
+		pt.reportError(err)
+	} else {
+		pt.reportDone()
+	}
+}
+
+// Event 获取当前进度事件
+func (pt *ProgressTracker) Event() ProgressEvent {
+	return ProgressEvent{
+		ID:         pt.id,
+		Name:       pt.Name,
+		Downloaded: pt.BytesCount.Load(),
+		Total:      pt.FileSize,
+		Offset:     pt.StartOffset,
+		Speed:      pt.GetSpeed(),
+		Elapsed:    time.Since(pt.StartTime),
+		Segments:   pt.SegmentBytes(),
+	}
+}
+
+// report 向进度报告器发送事件，final 为 true 时为结束事件，结束后不再发送任何事件
+func (pt *ProgressTracker) report(final bool, send func(ProgressEvent)) {
+	pt.reportMu.Lock()
+	defer pt.reportMu.Unlock()
+	if pt.finished {
+		return
+	}
+	if final {
+		pt.finished = true
+	}
+	send(pt.Event())
+}
+
+// reportStart 报告开始下载
+func (pt *ProgressTracker) reportStart() {
+	pt.report(false, pt.Reporter.Start)
+}
+
+// reportDone 报告下载完成
+func (pt *ProgressTracker) reportDone() {
+	pt.report(true, pt.Reporter.Done)
+}
+
+// reportError 报告下载中断
+func (pt *ProgressTracker) reportError(err error) {
+	pt.report(true, func(event ProgressEvent) {
+		event.Err = err
+		pt.Reporter.Error(event)
+	})
 }
 
 // GetCountingWriter 获取计数Writer
@@ -57,20 +136,69 @@ func (pt *ProgressTracker) GetCountingWriter(w io.Writer) io.Writer {
 	}
 }
 
-// MonitorSpeed 监控下载速度
+// SetSegments 设置分段数量，需要在获取分段Writer之前调用
+func (pt *ProgressTracker) SetSegments(count int) {
+	pt.segments = make([]atomic.Int64, count)
+}
+
+// SegmentWriter 获取分段的计数Writer，写入的字节同时计入分段及文件的已下载字节数
+func (pt *ProgressTracker) SegmentWriter(index int, w io.Writer) io.Writer {
+	return &CountingWriter{
+		Writer:     pt.GetCountingWriter(w),
+		BytesCount: &pt.segments[index],
+	}
+}
+
+// ResetSegment 清零分段的已下载字节数，用于从其他下载源重新获取该分段
+func (pt *ProgressTracker) ResetSegment(index int) {
+	pt.BytesCount.Add(-pt.segments[index].Swap(0))
+}
+
+// SegmentBytes 获取每个分段已下载的字节数，不是分段下载时返回nil
+func (pt *ProgressTracker) SegmentBytes() []int64 {
+	if len(pt.segments) == 0 {
+		return nil
+	}
+	counts := make([]int64, len(pt.segments))
+	for i := range pt.segments {
+		counts[i] = pt.segments[i].Load()
+	}
+	return counts
+}
+
+// MonitorSpeed 监控下载速度，宽限时间后每个检测间隔内的平均速度低于最小要求时取消下载
 func (pt *ProgressTracker) MonitorSpeed() {
-	speedCheckTicker := time.NewTicker(SpeedCheckInterval * time.Second)
+	policy := pt.Policy
+	if policy.MinSpeed <= 0 || policy.CheckInterval <= 0 {
+		return
+	}
+
+	// 宽限时间内不检测速度
+	if policy.GracePeriod > 0 {
+		select {
+		case <-time.After(policy.GracePeriod):
+		case <-pt.Done:
+			return
+		}
+	}
+
+	speedCheckTicker := time.NewTicker(policy.CheckInterval)
 	defer speedCheckTicker.Stop()
 
+	lastSize := pt.BytesCount.Load()
 	for {
 		select {
 		case <-speedCheckTicker.C:
-			// 检查当前下载速度是否低于最小要求
-			if pt.Speed < MinRequiredSpeed {
+			// 检查检测间隔内的平均下载速度是否低于最小要求
+			currentSize := pt.BytesCount.Load()
+			speed := float64(currentSize-lastSize) / policy.CheckInterval.Seconds()
+			lastSize = currentSize
+			if speed < policy.MinSpeed {
 				// 提示用户当前速度过低并取消下载
-				fmt.Printf("\r    下载已取消: 速度过低 (%s/s)，低于最小要求 (%s/s)，网络可能存在问题\n",
-					formatSize(int64(pt.Speed)),
-					formatSize(int64(MinRequiredSpeed)))
+				pt.reportError(msgError("tracker.low_speed", FormatSize(int64(speed))))
+				pt.Logger.Warnf("tracker.low_speed_cancelled",
+					FormatSize(int64(speed)),
+					FormatSize(int64(policy.MinSpeed)))
 
 				// 记录取消原因
 				pt.CancelReason.Store(ErrLowSpeed)
@@ -85,43 +213,34 @@ func (pt *ProgressTracker) MonitorSpeed() {
 	}
 }
 
-// DisplayProgress 显示下载进度
+// Start 报告开始下载，需要在启动 DisplayProgress 之前调用
+func (pt *ProgressTracker) Start() {
+	pt.reportStart()
+}
+
+// DisplayProgress 定期向进度报告器报告下载进度
 func (pt *ProgressTracker) DisplayProgress() {
 	updateInterval := time.Duration(ProgressUpdateInterval) * time.Millisecond
 
-	if pt.FileSize > 0 {
-		// 已知文件大小的情况
-		ticker := time.NewTicker(updateInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				pt.updateSpeed()
-				pt.displayKnownSizeProgress()
-			case <-pt.Done:
-				return
-			}
-		}
-	} else {
-		// 未知文件大小的情况
-		ticker := time.NewTicker(updateInterval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				pt.updateSpeed()
-				pt.displayUnknownSizeProgress()
-			case <-pt.Done:
-				return
-			}
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pt.updateSpeed()
+			pt.report(false, pt.Reporter.Progress)
+		case <-pt.Done:
+			return
 		}
 	}
 }
 
 // updateSpeed 更新下载速度
 func (pt *ProgressTracker) updateSpeed() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
 	// 使用原子变量读取当前下载大小
 	currentSize := pt.BytesCount.Load()
 
@@ -143,57 +262,11 @@ func (pt *ProgressTracker) updateSpeed() {
 	}
 }
 
-// displayKnownSizeProgress 显示已知文件大小的下载进度
-func (pt *ProgressTracker) displayKnownSizeProgress() {
-	currentSize := pt.BytesCount.Load()
-	progress := float64(currentSize) / float64(pt.FileSize) * 100
-	speedStr := formatSize(int64(pt.Speed)) + "/s"
-
-	if pt.Speed > MinValidSpeed {
-		// 只有当速度大于最小有效值时才计算剩余时间
-		remainingBytes := pt.FileSize - currentSize
-		remainingSeconds := float64(remainingBytes) / pt.Speed
-		// 限制最大预估时间为24小时，避免不合理的估计
-		if remainingSeconds > 86400 { // 24小时 = 86400秒
-			remainingSeconds = 86400
-		}
-		remainingTime := time.Duration(remainingSeconds) * time.Second
-
-		fmt.Printf("\r    下载进度: %.1f%% (%s/%s) 速度: %s 剩余时间: %s                    ",
-			progress,
-			formatSize(currentSize),
-			formatSize(pt.FileSize),
-			speedStr,
-			formatDuration(remainingTime))
-	} else if pt.Speed > 0 {
-		// 速度极低但不为0，显示速度但不显示剩余时间
-		fmt.Printf("\r    下载进度: %.1f%% (%s/%s) 速度: %s 剩余时间: 未知                    ",
-			progress,
-			formatSize(currentSize),
-			formatSize(pt.FileSize),
-			speedStr)
-	} else {
-		// 速度为0，等待恢复
-		fmt.Printf("\r    下载进度: %.1f%% (%s/%s) 等待数据传输...                    ",
-			progress,
-			formatSize(currentSize),
-			formatSize(pt.FileSize))
-	}
-}
-
-// displayUnknownSizeProgress 显示未知文件大小的下载进度
-func (pt *ProgressTracker) displayUnknownSizeProgress() {
-	currentSize := pt.BytesCount.Load()
-	speedStr := formatSize(int64(pt.Speed)) + "/s"
-
-	if pt.Speed > MinValidSpeed {
-		fmt.Printf("\r    已下载: %s 速度: %s                    ",
-			formatSize(currentSize),
-			speedStr)
-	} else {
-		fmt.Printf("\r    已下载: %s 等待数据传输...                    ",
-			formatSize(currentSize))
-	}
+// GetSpeed 获取当前下载速度
+func (pt *ProgressTracker) GetSpeed() float64 {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.Speed
 }
 
 // DisplaySummary 显示下载摘要
@@ -203,17 +276,17 @@ func (pt *ProgressTracker) DisplaySummary() {
 		return
 	}
 
-	// 清除进度条行
-	fmt.Print("\r                                                                                                                                    \r")
+	// 报告下载完成，移除进度条行
+	pt.reportDone()
 
 	// 显示总下载时间和平均速度
 	totalTime := time.Since(pt.StartTime)
 	totalBytes := pt.BytesCount.Load()
-	avgSpeed := float64(totalBytes) / totalTime.Seconds()
-	fmt.Printf("    下载完成: 总大小 %s, 用时 %s, 平均速度 %s/s\n",
-		formatSize(totalBytes),
-		formatDuration(totalTime),
-		formatSize(int64(avgSpeed)))
+	avgSpeed := float64(totalBytes-pt.StartOffset) / totalTime.Seconds()
+	pt.Logger.Infof("tracker.summary",
+		FormatSize(totalBytes),
+		FormatDuration(totalTime),
+		FormatSize(int64(avgSpeed)))
 }
 
 // GetCancelReason 获取下载取消的原因
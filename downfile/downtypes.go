@@ -1,33 +1,116 @@
 package downfile
 
+import "time"
+
 // DownItem 下载项目结构
 type DownItem struct {
-	Module       string   `yaml:"module"`
-	FileName     string   `yaml:"filename"`
-	DownloadURLs []string `yaml:"download-urls"`
-	KeepUpdated  bool     `yaml:"keep-updated"`
-	Enable       bool     `yaml:"enable"`
+	Module         string         `yaml:"module"`
+	FileName       string         `yaml:"filename"`
+	DownloadURLs   []string       `yaml:"download-urls"`
+	KeepUpdated    bool           `yaml:"keep-updated"`
+	Enable         bool           `yaml:"enable"`
+	SHA256         string         `yaml:"sha256"`               // 期望的SHA256校验值（可选）
+	SHA512         string         `yaml:"sha512"`               // 期望的SHA512校验值（可选）
+	MD5            string         `yaml:"md5"`                  // 期望的MD5校验值（可选）
+	ChecksumURL    string         `yaml:"checksum-url"`         // 校验文件URL，支持 <file>.sha256 或 SHA256SUMS 格式（可选）
+	ChecksumsFile  string         `yaml:"checksums-file"`       // 覆盖多个文件的校验清单URL，按文件名查找（可选）
+	MinSpeed       float64        `yaml:"min-speed"`            // 最小要求下载速度（bytes/second），0使用全局设置，负数禁用速度检测
+	SpeedInterval  int            `yaml:"speed-check-interval"` // 下载速度检测间隔（秒），0使用全局设置
+	SpeedGrace     int            `yaml:"speed-grace-period"`   // 开始下载后不检测速度的宽限时间（秒），0使用全局设置
+	Timeout        time.Duration  `yaml:"timeout"`              // 下载项的最长处理时间（如 10m），包括所有下载源及重试，0表示不限制
+	Extract        *ExtractConfig `yaml:"extract"`              // 下载完成后的解压配置（可选）
+	MirrorStrategy string         `yaml:"mirror-strategy"`      // 下载源排序策略：ordered|fastest|healthiest|random，为空时使用全局设置
+	Race           bool           `yaml:"race"`                 // 是否在下载前并发探测所有下载源，从最先响应的下载源下载
+	GitHubRelease  string         `yaml:"github-release"`       // GitHub 仓库（owner/repo），通过 Releases API 解析下载地址（可选）
+	Asset          string         `yaml:"asset"`                // Release 文件名匹配模式，支持通配符，如 *.mmdb
+	Tag            string         `yaml:"tag"`                  // Release 标签，支持通配符，为空时使用最新的 Release
+	Prerelease     bool           `yaml:"prerelease"`           // 是否包含预发布版本
+	Segments       int            `yaml:"segments"`             // 分段下载的并行连接数，0使用全局设置，1表示不分段
+	SegmentMirrors bool           `yaml:"segment-mirrors"`      // 分段下载时是否同时从其他文件大小一致的下载源获取分段
+	Tags           []string       `yaml:"tags"`                 // 标签，用于在命令行中选择下载项（可选）
 }
 
 // DownConfig 配置文件结构
 type DownConfig map[string][]DownItem
 
+// RewriteRulesKey 配置文件中保存下载地址改写规则的顶层键，不作为配置组
+const RewriteRulesKey = "rewrite-rules"
+
+// ConfigFile 完整的配置文件内容
+type ConfigFile struct {
+	Version      int           // 配置文件格式版本
+	Settings     Settings      // 全局设置（旧版格式为空）
+	Groups       DownConfig    // 配置组（已应用 defaults）
+	RewriteRules []RewriteRule // 下载地址改写规则
+}
+
 // 常量定义
 const (
 	// MinValidSpeed 最小有效下载速度 (bytes/second)，低于此值视为停滞
 	MinValidSpeed = 10.0
-	// MinRequiredSpeed 最小要求下载速度 (bytes/second)，低于此值判定为网络问题
-	MinRequiredSpeed = 1024.0 // 1KB/s
-	// SpeedCheckInterval 下载速度检测间隔（秒）
-	SpeedCheckInterval = 5
 	// ProgressUpdateInterval 下载进度更新间隔（毫秒）
 	ProgressUpdateInterval = 500
 	// DownloadBufferSize 下载缓冲区大小
 	DownloadBufferSize = 32 * 1024 // 32KB
+	// MaxChecksumFileSize 校验清单文件的最大大小
+	MaxChecksumFileSize = 4 * 1024 * 1024 // 4MB
 )
 
-// CacheExpireHours 缓存过期时间（小时）
-var CacheExpireHours = 24.0
+// MinRequiredSpeed 最小要求下载速度 (bytes/second)，低于此值判定为网络问题，小于等于0时禁用速度检测
+var MinRequiredSpeed = 1024.0 // 1KB/s
+
+// SpeedCheckInterval 下载速度检测间隔（秒）
+var SpeedCheckInterval = 5
+
+// SpeedGracePeriod 开始下载后不检测速度的宽限时间（秒）
+var SpeedGracePeriod = 0
+
+// SpeedPolicy 低速检测策略
+type SpeedPolicy struct {
+	MinSpeed      float64       // 最小要求下载速度 (bytes/second)，小于等于0时禁用检测
+	CheckInterval time.Duration // 检测间隔
+	GracePeriod   time.Duration // 宽限时间
+}
+
+// DefaultSpeedPolicy 获取使用全局设置的低速检测策略
+func DefaultSpeedPolicy() SpeedPolicy {
+	return SpeedPolicy{
+		MinSpeed:      MinRequiredSpeed,
+		CheckInterval: time.Duration(SpeedCheckInterval) * time.Second,
+		GracePeriod:   time.Duration(SpeedGracePeriod) * time.Second,
+	}
+}
+
+// ItemSpeedPolicy 获取下载项的低速检测策略，未配置的字段使用全局设置
+func ItemSpeedPolicy(item DownItem) SpeedPolicy {
+	return DefaultSpeedPolicy().ForItem(item)
+}
+
+// ForItem 获取下载项的低速检测策略，下载项未配置的字段使用当前策略
+func (policy SpeedPolicy) ForItem(item DownItem) SpeedPolicy {
+	if item.MinSpeed != 0 {
+		policy.MinSpeed = item.MinSpeed
+	}
+	if item.SpeedInterval > 0 {
+		policy.CheckInterval = time.Duration(item.SpeedInterval) * time.Second
+	}
+	if item.SpeedGrace > 0 {
+		policy.GracePeriod = time.Duration(item.SpeedGrace) * time.Second
+	}
+	return policy
+}
+
+// UserAgent 请求使用的User-Agent，避免某些服务器的限制
+var UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// DefaultCacheExpireHours 默认缓存过期时间（小时）
+const DefaultCacheExpireHours = 24.0
+
+// PartialExpireHours 未完成下载文件的保留时间（小时），超过后清理时删除
+var PartialExpireHours = 72.0
+
+// MaxExtractSize 解压出的文件总大小上限（字节），超过时解压失败，防止压缩炸弹占满磁盘，小于等于0时不限制
+var MaxExtractSize int64 = 4 << 30 // 4GB
 
 // CacheFileName 缓存文件名
 var CacheFileName = ".download_cache.json"
@@ -38,4 +121,18 @@ const (
 	ErrResourceNotFound = "RESOURCE_NOT_FOUND"
 	// ErrLowSpeed 下载速度过低错误
 	ErrLowSpeed = "DOWNLOAD_SPEED_TOO_LOW"
+	// ErrChecksumMismatch 文件校验值不匹配错误
+	ErrChecksumMismatch = "CHECKSUM_MISMATCH"
+	// ErrNotModified 文件未修改（304），条件请求确认本地文件是最新的
+	ErrNotModified = "NOT_MODIFIED"
+	// ErrRangeNotSatisfiable 续传范围无效错误（416）
+	ErrRangeNotSatisfiable = "RANGE_NOT_SATISFIABLE"
+	// ErrTimeout 下载超时错误（超过下载项或整个运行的时间限制）
+	ErrTimeout = "DOWNLOAD_TIMEOUT"
+	// ErrExtractFailed 压缩包解压失败错误
+	ErrExtractFailed = "EXTRACT_FAILED"
+	// ErrChecksumUnavailable 无法获取文件的期望校验值错误
+	ErrChecksumUnavailable = "CHECKSUM_UNAVAILABLE"
+	// ErrReleaseUnavailable 无法解析 GitHub Release 下载地址错误
+	ErrReleaseUnavailable = "RELEASE_UNAVAILABLE"
 )
@@ -1,28 +1,21 @@
 package downfile
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载配置文件中的配置组
 func LoadConfig(filename string) (DownConfig, error) {
-	data, err := os.ReadFile(filename)
+	config, err := LoadConfigFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("读取配置文件失败: %w", err)
-	}
-
-	var config DownConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("解析YAML失败: %w", err)
+		return nil, err
 	}
-
-	return config, nil
+	return config.Groups, nil
 }
 
 // FileExists 检查文件是否存在
@@ -40,25 +33,25 @@ func ConvertGitHubURL(url string) string {
 	return strings.Replace(strings.Replace(url, "github.com", "raw.githubusercontent.com", 1), "/blob/", "/", 1)
 }
 
-// formatDuration 格式化时间为易读格式
-func formatDuration(d time.Duration) string {
+// FormatDuration 格式化时间为易读格式
+func FormatDuration(d time.Duration) string {
 	d = d.Round(time.Second)
 
 	if d.Hours() >= 1 {
 		h := int(d.Hours())
 		m := int(d.Minutes()) % 60
 		s := int(d.Seconds()) % 60
-		return fmt.Sprintf("%d小时%d分%d秒", h, m, s)
+		return Msg("duration.hms", h, m, s)
 	} else if d.Minutes() >= 1 {
 		m := int(d.Minutes())
 		s := int(d.Seconds()) % 60
-		return fmt.Sprintf("%d分%d秒", m, s)
+		return Msg("duration.ms", m, s)
 	}
-	return fmt.Sprintf("%d秒", int(d.Seconds()))
+	return Msg("duration.s", int(d.Seconds()))
 }
 
-// 格式化大小为易读格式
-func formatSize(bytes int64) string {
+// FormatSize 格式化大小为易读格式
+func FormatSize(bytes int64) string {
 	const (
 		KB = 1024
 		MB = 1024 * KB
@@ -113,26 +106,77 @@ func FilterEnableItems(items []DownItem) []DownItem {
 	return enabledItems
 }
 
-// CleanupIncompleteDownloads 清理下载目录下未完成的下载文件（以 .download 结尾的文件）
-func CleanupIncompleteDownloads(downloadDir string) error {
+// CleanupIncompleteDownloads 清理下载目录下无法续传的未完成下载文件
+// 只删除孤立的（缺少续传元数据或数据文件）以及超过 PartialExpireHours 未更新的未完成下载文件，删除失败时通过 log 输出警告
+func CleanupIncompleteDownloads(downloadDir string, log MsgLogger) error {
 	// 检查下载目录是否存在
 	if _, err := os.Stat(downloadDir); os.IsNotExist(err) {
 		return nil // 目录不存在，直接返回
 	}
 	// 查找所有 .download 文件
-	files, err := FindFilesBySuffix(downloadDir, ".download")
+	files, err := FindFilesBySuffix(downloadDir, PartialSuffix)
 	if err != nil {
-		return fmt.Errorf("查找未完成下载文件失败: %w", err)
+		return msgError("cleanup.find_failed", err)
 	}
-	// 删除每个文件
+	// 删除无法续传的文件，其他进程正在写入的除外
 	for _, file := range files {
+		if !isStalePartial(file) || partialInUse(file) {
+			continue
+		}
 		if err := os.Remove(file); err != nil {
-			fmt.Fprintf(os.Stderr, "警告: 删除未完成下载文件失败 %s: %v\n", file, err)
+			log.Warnf("cleanup.remove_failed", file, err)
+		}
+		os.Remove(strings.TrimSuffix(file, PartialSuffix) + PartialMetaSuffix)
+	}
+	// 删除缺少数据文件的续传元数据
+	metaFiles, err := FindFilesBySuffix(downloadDir, PartialMetaSuffix)
+	if err != nil {
+		return msgError("cleanup.find_failed", err)
+	}
+	for _, metaFile := range metaFiles {
+		if !FileExists(strings.TrimSuffix(metaFile, PartialMetaSuffix) + PartialSuffix) {
+			os.Remove(metaFile)
 		}
 	}
 	return nil
 }
 
+// RemovePartialDownloads 删除下载目录下所有未完成下载文件及续传元数据（包括可以续传的），返回删除的文件数量
+// 其他进程正在下载的文件不删除，无人持有的锁文件随未完成下载文件一起删除
+func RemovePartialDownloads(downloadDir string) (int, error) {
+	if _, err := os.Stat(downloadDir); os.IsNotExist(err) {
+		return 0, nil
+	}
+	removed := 0
+	for _, suffix := range []string{PartialSuffix, PartialMetaSuffix} {
+		files, err := FindFilesBySuffix(downloadDir, suffix)
+		if err != nil {
+			return removed, msgError("cleanup.find_failed", err)
+		}
+		for _, file := range files {
+			if partialInUse(strings.TrimSuffix(file, suffix) + PartialSuffix) {
+				continue
+			}
+			if err := os.Remove(file); err != nil {
+				return removed, msgError("cleanup.remove_partial_failed", file, err)
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// isStalePartial 判断未完成下载文件是否已孤立或过期
+func isStalePartial(partialPath string) bool {
+	metaPath := strings.TrimSuffix(partialPath, PartialSuffix) + PartialMetaSuffix
+	meta, err := loadPartialMeta(metaPath)
+	if err != nil {
+		// 没有续传元数据（包括旧版本遗留的临时文件），无法续传
+		return true
+	}
+	return meta.validator() == "" || time.Since(meta.UpdatedAt).Hours() > PartialExpireHours
+}
+
 // FindFilesBySuffix 递归查找指定目录下所有以后缀 suffix 结尾的文件
 func FindFilesBySuffix(root, suffix string) ([]string, error) {
 	var files []string
@@ -148,6 +192,7 @@ func FindFilesBySuffix(root, suffix string) ([]string, error) {
 	return files, err
 }
 
+// DownloadFileSimple 使用默认设置下载单个文件，不记录下载缓存
 func DownloadFileSimple(url string, proxy string, storePath string) error {
 	// 创建HTTP客户端配置
 	clientConfig := &ClientConfig{
@@ -159,9 +204,9 @@ func DownloadFileSimple(url string, proxy string, storePath string) error {
 	if err != nil {
 		return err
 	}
-	err = downloadFile(httpClient, url, storePath, false)
+	downloader, err := NewDownloader(WithHTTPClient(httpClient), WithCache(nopCache{}))
 	if err != nil {
 		return err
 	}
-	return nil
+	return downloader.DownloadURL(context.Background(), url, storePath)
 }
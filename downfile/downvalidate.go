@@ -0,0 +1,372 @@
+package downfile
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置检查问题的级别
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+)
+
+// ConfigIssue 配置检查发现的问题，Line/Column 为问题在配置文件中的位置（从1开始，未知时为0）
+type ConfigIssue struct {
+	Level   string
+	Line    int
+	Column  int
+	Message string
+}
+
+// String 格式化为 行:列: 级别: 消息
+func (i ConfigIssue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Level, i.Message)
+}
+
+// HasErrors 判断检查结果中是否有错误级别的问题
+func HasErrors(issues []ConfigIssue) bool {
+	for _, issue := range issues {
+		if issue.Level == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+// configValidator 配置检查器
+type configValidator struct {
+	outputDir string
+	issues    []ConfigIssue
+	items     map[string][]*yaml.Node // 配置组 -> 下载项节点，与解析结果中的下载项一一对应
+}
+
+// ValidateConfigFile 检查配置文件，outputDir 用于检查下载文件路径
+func ValidateConfigFile(filename, outputDir string) ([]ConfigIssue, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, msgError("config.read_failed", err)
+	}
+	return ValidateConfig(data, outputDir), nil
+}
+
+// ValidateConfig 严格检查配置内容：拒绝未知字段，并检查下载地址、文件名及模块名
+func ValidateConfig(data []byte, outputDir string) []ConfigIssue {
+	v := &configValidator{outputDir: outputDir, items: make(map[string][]*yaml.Node)}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		v.add(IssueError, nil, "validate.syntax", err)
+		return v.issues
+	}
+	if len(document.Content) == 0 {
+		v.add(IssueError, nil, "validate.empty")
+		return v.issues
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		v.add(IssueError, root, "validate.root_type")
+		return v.issues
+	}
+
+	// 检查字段名称及类型
+	if isSchemaConfig(mappingValue(root, "version"), mappingValue(root, "groups")) {
+		v.checkSchema(root)
+	} else {
+		v.checkLegacy(root)
+	}
+
+	// 检查下载项内容，未知字段不影响解析，与字段检查的问题一并输出
+	config, err := ParseConfig(data)
+	if err != nil {
+		// 字段类型错误已带位置输出，不再重复解析错误
+		if !HasErrors(v.issues) {
+			v.add(IssueError, nil, "validate.parse", err)
+		}
+		return v.sorted()
+	}
+	v.checkItems(config)
+	return v.sorted()
+}
+
+// sorted 按问题在配置文件中的位置排序，位置未知的问题排在最前
+func (v *configValidator) sorted() []ConfigIssue {
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		if v.issues[i].Line != v.issues[j].Line {
+			return v.issues[i].Line < v.issues[j].Line
+		}
+		return v.issues[i].Column < v.issues[j].Column
+	})
+	return v.issues
+}
+
+// add 记录一个问题，node 为nil时不记录位置
+func (v *configValidator) add(level string, node *yaml.Node, key string, args ...any) {
+	issue := ConfigIssue{Level: level, Message: Msg(key, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// checkLegacy 检查旧版格式：顶层的 rewrite-rules 为改写规则，其余键为下载项列表
+func (v *configValidator) checkLegacy(root *yaml.Node) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value == RewriteRulesKey {
+			v.checkFields(value, reflect.TypeOf([]RewriteRule{}))
+			continue
+		}
+		v.checkItemList(key.Value, value)
+	}
+}
+
+// checkSchema 检查当前格式
+func (v *configValidator) checkSchema(root *yaml.Node) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "version":
+			v.checkDecode(value, new(int))
+		case "settings":
+			v.checkFields(value, reflect.TypeOf(Settings{}))
+		case "defaults":
+			v.checkFields(value, reflect.TypeOf(DownItem{}))
+		case RewriteRulesKey:
+			v.checkFields(value, reflect.TypeOf([]RewriteRule{}))
+		case "groups":
+			if value.Kind != yaml.MappingNode {
+				v.add(IssueError, value, "validate.groups_type")
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				v.checkGroup(value.Content[j].Value, value.Content[j+1])
+			}
+		default:
+			v.add(IssueError, key, "validate.unknown_field", key.Value)
+		}
+	}
+}
+
+// checkGroup 检查当前格式的配置组（下载项列表或包含 defaults/items 的映射）
+func (v *configValidator) checkGroup(groupName string, node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.checkItemList(groupName, node)
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "defaults":
+			v.checkFields(value, reflect.TypeOf(DownItem{}))
+		case "items":
+			v.checkItemList(groupName, value)
+		default:
+			v.add(IssueError, key, "validate.unknown_field", key.Value)
+		}
+	}
+}
+
+// checkItemList 检查下载项列表，并记录下载项节点供内容检查使用
+func (v *configValidator) checkItemList(groupName string, node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.add(IssueError, node, "validate.items_type", groupName)
+		return
+	}
+	for _, itemNode := range node.Content {
+		v.checkFields(itemNode, reflect.TypeOf(DownItem{}))
+		v.items[groupName] = append(v.items[groupName], itemNode)
+	}
+}
+
+// checkFields 按结构体的 yaml 标签递归检查字段名称，并检查字段类型
+func (v *configValidator) checkFields(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, child := range node.Content {
+			v.checkFields(child, t.Elem())
+		}
+		return
+	case t.Kind() == reflect.Struct && t != reflect.TypeOf(yaml.Node{}) && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				v.add(IssueError, key, "validate.unknown_field", key.Value)
+				continue
+			}
+			v.checkFields(value, field.Type)
+		}
+		return
+	}
+	v.checkDecode(node, reflect.New(t).Interface())
+}
+
+// checkDecode 检查节点能否解析为指定类型
+func (v *configValidator) checkDecode(node *yaml.Node, out any) {
+	if err := node.Decode(out); err != nil {
+		v.add(IssueError, node, "validate.type", typeErrorMessage(err))
+	}
+}
+
+// typeErrorMessage 去掉 yaml 错误信息中的前缀及行号（位置已单独输出）
+func typeErrorMessage(err error) string {
+	message := strings.TrimPrefix(err.Error(), "yaml: unmarshal errors:\n")
+	message = strings.TrimSpace(message)
+	if strings.HasPrefix(message, "line ") {
+		if index := strings.Index(message, ": "); index >= 0 {
+			message = message[index+2:]
+		}
+	}
+	return message
+}
+
+// yamlFields 获取结构体的 yaml 字段名称
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// mappingValue 获取映射节点中键对应的值节点，不存在时返回nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// itemLocation 下载项的位置，用于提示重复的下载项
+type itemLocation struct {
+	group string
+	node  *yaml.Node
+}
+
+// checkItems 检查解析后的下载项（已应用 defaults）
+func (v *configValidator) checkItems(config *ConfigFile) {
+	outputDir, _ := filepath.Abs(v.outputDir)
+	targets := make(map[string]itemLocation)
+	modules := make(map[string]itemLocation)
+
+	for _, groupName := range sortedGroupNames(config.Groups) {
+		nodes := v.items[groupName]
+		for i, item := range config.Groups[groupName] {
+			if i >= len(nodes) {
+				break
+			}
+			node := nodes[i]
+			v.checkItem(groupName, item, node, outputDir)
+
+			// 检查重复的模块名
+			if item.Module != "" {
+				if first, exists := modules[item.Module]; exists {
+					if first.group == groupName {
+						v.add(IssueError, node, "validate.duplicate_module", item.Module, first.node.Line)
+					} else {
+						v.add(IssueWarning, node, "validate.duplicate_module_group", item.Module, first.group, first.node.Line)
+					}
+				} else {
+					modules[item.Module] = itemLocation{group: groupName, node: node}
+				}
+			}
+
+			// 检查重复的下载目标文件
+			if item.FileName != "" {
+				target, _ := filepath.Abs(GetItemFilePath(item.FileName, v.outputDir))
+				if first, exists := targets[target]; exists {
+					v.add(IssueError, fieldNode(node, "filename"), "validate.duplicate_filename", item.FileName, first.group, first.node.Line)
+				} else {
+					targets[target] = itemLocation{group: groupName, node: node}
+				}
+			}
+		}
+	}
+}
+
+// checkItem 检查单个下载项
+func (v *configValidator) checkItem(groupName string, item DownItem, node *yaml.Node, outputDir string) {
+	if item.Module == "" {
+		v.add(IssueError, node, "validate.module_required", groupName)
+	}
+
+	// 检查文件路径
+	if item.FileName == "" {
+		v.add(IssueError, node, "validate.filename_required", item.Module)
+	} else if filepath.IsAbs(item.FileName) {
+		if !pathWithin(outputDir, item.FileName) {
+			v.add(IssueWarning, fieldNode(node, "filename"), "validate.filename_absolute", item.FileName)
+		}
+	} else if target, _ := filepath.Abs(filepath.Join(outputDir, item.FileName)); !pathWithin(outputDir, target) {
+		v.add(IssueError, fieldNode(node, "filename"), "validate.filename_escape", item.FileName)
+	}
+	if item.Extract != nil && item.Extract.Dest != "" && !filepath.IsAbs(item.Extract.Dest) {
+		if target, _ := filepath.Abs(filepath.Join(outputDir, item.Extract.Dest)); !pathWithin(outputDir, target) {
+			v.add(IssueError, fieldNode(mappingValue(node, "extract"), "dest"), "validate.extract_escape", item.Extract.Dest)
+		}
+	}
+
+	// 检查下载地址
+	if len(item.DownloadURLs) == 0 && item.GitHubRelease == "" {
+		v.add(IssueError, node, "validate.urls_required", item.Module)
+	}
+	urlNodes := mappingValue(node, "download-urls")
+	for i, downloadURL := range item.DownloadURLs {
+		urlNode := node
+		if urlNodes != nil && urlNodes.Kind == yaml.SequenceNode && i < len(urlNodes.Content) {
+			urlNode = urlNodes.Content[i]
+		}
+		parsed, err := url.Parse(downloadURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			v.add(IssueError, urlNode, "validate.invalid_url", downloadURL)
+		}
+	}
+	if item.GitHubRelease != "" && item.Asset == "" {
+		v.add(IssueError, fieldNode(node, "github-release"), "release.asset_required", item.GitHubRelease)
+	}
+	if !ValidMirrorStrategy(item.MirrorStrategy) {
+		v.add(IssueError, fieldNode(node, "mirror-strategy"), "validate.invalid_strategy", item.MirrorStrategy)
+	}
+}
+
+// fieldNode 获取映射节点中字段的值节点，不存在时返回映射节点本身
+func fieldNode(node *yaml.Node, key string) *yaml.Node {
+	if value := mappingValue(node, key); value != nil {
+		return value
+	}
+	return node
+}
+
+// pathWithin 判断 target 是否位于 dir 目录下
+func pathWithin(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,67 @@
+package downfile
+
+import (
+	"testing"
+)
+
+func TestValidateConfigReportsAllIssues(t *testing.T) {
+	config := `version: 1
+groups:
+  geo:
+    - module: city
+      filename: ../city.mmdb
+      download-url: [https://example.com/city.mmdb]
+      keep_updated: true
+    - module: asn
+      filename: asn.mmdb
+      download-urls: ["ftp://example.com/asn.mmdb"]
+`
+	want := []ConfigIssue{
+		{Level: IssueError, Line: 4, Column: 7, Message: Msg("validate.urls_required", "city")},
+		{Level: IssueError, Line: 5, Column: 17, Message: Msg("validate.filename_escape", "../city.mmdb")},
+		{Level: IssueError, Line: 6, Column: 7, Message: Msg("validate.unknown_field", "download-url")},
+		{Level: IssueError, Line: 7, Column: 7, Message: Msg("validate.unknown_field", "keep_updated")},
+		{Level: IssueError, Line: 10, Column: 23, Message: Msg("validate.invalid_url", "ftp://example.com/asn.mmdb")},
+	}
+
+	issues := ValidateConfig([]byte(config), t.TempDir())
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %v, want %v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %v, want %v", i, issues[i], want[i])
+		}
+	}
+}
+
+func TestValidateConfigTypeError(t *testing.T) {
+	config := `version: 1
+groups:
+  geo:
+    - module: city
+      filename: city.mmdb
+      download-urls: [https://example.com/city.mmdb]
+      keep-updated: sometimes
+`
+	issues := ValidateConfig([]byte(config), t.TempDir())
+	if len(issues) != 1 || issues[0].Line != 7 || issues[0].Level != IssueError {
+		t.Errorf("issues = %v, want a single type error on line 7", issues)
+	}
+}
+
+func TestValidateConfigLegacyGroupNames(t *testing.T) {
+	// 旧版格式中名为 version、groups 的配置组按旧版格式检查
+	config := `version:
+  - module: a
+    filename: a.bin
+    download-urls: [https://example.com/a.bin]
+groups:
+  - module: b
+    filename: b.bin
+    download-urls: [https://example.com/b.bin]
+`
+	if issues := ValidateConfig([]byte(config), t.TempDir()); len(issues) != 0 {
+		t.Errorf("issues = %v, want none", issues)
+	}
+}
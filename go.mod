@@ -2,9 +2,9 @@ module github.com/winezer0/downtools
 
 go 1.21
 
-require gopkg.in/yaml.v3 v3.0.1
-
 require (
-	github.com/jessevdk/go-flags v1.6.1 // indirect
-	golang.org/x/sys v0.21.0 // indirect
+	github.com/jessevdk/go-flags v1.6.1
+	github.com/ulikunitz/xz v0.5.12
+	golang.org/x/sys v0.21.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,83 +1,403 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/winezer0/downtools/downfile"
 )
 
-// AppConfig 应用配置结构体
+// AppConfig 应用配置结构体，参数的帮助信息来自消息目录（help.<长参数名>），见 localizeHelp
 type AppConfig struct {
-	ConfigFile     string  `short:"c" long:"config" description:"配置文件路径" default:"config.yaml"`
-	OutputDir      string  `short:"o" long:"output" description:"下载文件保存目录" default:"downloads"`
-	ConnectTimeout int     `short:"t" long:"connect-timeout" description:"连接超时时间（秒）" default:"10"`
-	IdleTimeout    int     `short:"T" long:"idle-timeout" description:"空闲超时时间（秒）" default:"60"`
-	Retries        int     `short:"r" long:"retries" description:"下载失败重试次数" default:"1"`
-	KeepOld        bool    `short:"k" long:"keep-old" description:"保留旧文件（重命名为.old）"`
-	ForceUpdate    bool    `short:"f" long:"force" description:"强制更新，忽略缓存"`
-	ProxyURL       string  `short:"p" long:"proxy" description:"代理URL（支持http://和socks5://格式）" default:""`
-	CacheExpire    float64 `short:"E" long:"cache-expire" description:"缓存过期时间（小时）" default:"24"`
-	EnableAll      bool    `short:"e" long:"enable-all" description:"下载所有项 即使enable=false"`
-	Version        bool    `short:"v" long:"version" description:"显示版本信息"`
+	ConfigFile     string        `short:"c" long:"config" default:"config.yaml"`
+	OutputDir      string        `short:"o" long:"output" default:"downloads"`
+	ConnectTimeout int           `short:"t" long:"connect-timeout" default:"10"`
+	IdleTimeout    int           `short:"T" long:"idle-timeout" default:"60"`
+	Retries        int           `short:"r" long:"retries" default:"1"`
+	KeepOld        bool          `short:"k" long:"keep-old"`
+	ForceUpdate    bool          `short:"f" long:"force"`
+	ProxyURL       string        `short:"p" long:"proxy" default:""`
+	StateDir       string        `long:"state-dir"`
+	CacheExpire    float64       `short:"E" long:"cache-expire" default:"24"`
+	EnableAll      bool          `short:"e" long:"enable-all"`
+	Groups         []string      `short:"g" long:"group"`
+	Modules        []string      `short:"m" long:"module"`
+	Tags           []string      `long:"tag"`
+	Excludes       []string      `short:"x" long:"exclude"`
+	Concurrency    int           `short:"j" long:"concurrency" default:"1"`
+	MinSpeed       float64       `long:"min-speed" default:"1024"`
+	SpeedInterval  int           `long:"speed-interval" default:"5"`
+	SpeedGrace     int           `long:"speed-grace" default:"0"`
+	TotalTimeout   time.Duration `long:"total-timeout" default:"0"`
+	LockFile       string        `long:"lock-file" default:"downtools.lock"`
+	Locked         bool          `long:"locked"`
+	ReportFile     string        `long:"report"`
+	ReportFormat   string        `long:"report-format" choice:"json" choice:"junit" default:"json"`
+	FailOn         string        `long:"fail-on" choice:"error" choice:"missing" choice:"never" default:"error"`
+	Progress       string        `long:"progress" choice:"auto" choice:"tty" choice:"plain" choice:"json" choice:"none" default:"auto"`
+	MirrorStrategy string        `long:"mirror-strategy" choice:"ordered" choice:"fastest" choice:"healthiest" choice:"random" default:"ordered"`
+	Segments       int           `long:"segments" default:"1"`
+	GitHubAPI      string        `long:"github-api" default:"https://api.github.com"`
+	Probe          bool          `long:"probe"`
+	DryRun         bool          `long:"dry-run"`
+	PlanFormat     string        `long:"plan-format" choice:"text" choice:"json" default:"text"`
+	LogLevel       string        `long:"log-level" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info"`
+	LogFormat      string        `long:"log-format" choice:"text" choice:"json" default:"text"`
+	LogFile        string        `long:"log-file"`
+	Lang           string        `long:"lang" choice:"" choice:"zh" choice:"en"`
+	Version        bool          `short:"v" long:"version"`
 }
 
 const Version = "v0.0.9"
 
+// 进程退出码
+const (
+	ExitOK          = 0 // 所有下载项处理成功
+	ExitPartialFail = 1 // 部分下载项失败
+	ExitAllFailed   = 2 // 所有下载项都失败
+	ExitConfigError = 3 // 配置文件或HTTP客户端配置错误
+	ExitUsageError  = 4 // 命令行参数错误
+)
+
+// 失败判定策略
+const (
+	FailOnError   = "error"   // 下载失败或资源不存在都计为失败
+	FailOnMissing = "missing" // 仅在下载失败且本地没有可用文件时计为失败
+	FailOnNever   = "never"   // 不因下载结果失败
+)
+
+// appLog 主程序日志输出
+var appLog downfile.MsgLogger
+
 // DisplayConfig 显示应用配置信息
 func (config *AppConfig) DisplayConfig() {
-	fmt.Printf("自动下载工具 %s\n", Version)
-	fmt.Printf("配置文件: %s\n", config.ConfigFile)
-	fmt.Printf("输出目录: %s\n", config.OutputDir)
-	fmt.Printf("连接超时: %d秒\n", config.ConnectTimeout)
-	fmt.Printf("空闲超时: %d秒\n", config.IdleTimeout)
-	fmt.Printf("重试次数: %d次\n", config.Retries)
-	fmt.Printf("保留旧文件: %v\n", config.KeepOld)
-	fmt.Printf("使用代理: %s\n", config.ProxyURL)
-	fmt.Printf("启用强制更新: %v\n", config.ForceUpdate)
-	fmt.Printf("缓存过期时间: %v小时\n", config.CacheExpire)
-	fmt.Printf("下载未启用项: %v\n", config.EnableAll)
-	fmt.Println()
+	appLog.Infof("main.title", Version)
+	appLog.Infof("main.config_file", config.ConfigFile)
+	appLog.Infof("main.output_dir", config.OutputDir)
+	appLog.Infof("main.connect_timeout", config.ConnectTimeout)
+	appLog.Infof("main.idle_timeout", config.IdleTimeout)
+	appLog.Infof("main.retries", config.Retries)
+	appLog.Infof("main.keep_old", config.KeepOld)
+	appLog.Infof("main.proxy", config.ProxyURL)
+	appLog.Infof("main.force", config.ForceUpdate)
+	appLog.Infof("main.state_dir", config.stateDir())
+	appLog.Infof("main.cache_expire", config.CacheExpire)
+	appLog.Infof("main.enable_all", config.EnableAll)
+	if filter := config.taskFilter(); !filter.IsEmpty() {
+		appLog.Infof("main.filter", filter)
+	}
+	appLog.Infof("main.concurrency", config.Concurrency)
+	appLog.Infof("main.progress", config.Progress)
+	appLog.Infof("main.total_timeout", config.TotalTimeout)
+	if config.ReportFile != "" {
+		appLog.Infof("main.report", config.ReportFile, config.ReportFormat)
+	}
+	appLog.Infof("main.min_speed", config.MinSpeed, config.SpeedInterval, config.SpeedGrace)
+	appLog.Infof("main.mirror_strategy", config.MirrorStrategy)
+	appLog.Infof("main.segments", config.Segments)
+	if config.Locked {
+		appLog.Infof("main.locked", config.LockFile)
+	}
+	appLog.Infof("main.blank")
+}
+
+// stateDir 获取状态目录，未指定时使用输出目录下的默认状态目录
+func (config *AppConfig) stateDir() string {
+	if config.StateDir != "" {
+		return config.StateDir
+	}
+	return downfile.DefaultStateDir(config.OutputDir)
+}
+
+// openStateStore 打开输出目录的状态存储，migrate 为 true 时先迁移旧版用户主目录缓存中属于该输出目录的记录
+func (config *AppConfig) openStateStore(migrate bool) *downfile.FileCache {
+	store := downfile.NewStateStore(config.stateDir(), config.OutputDir, config.CacheExpire)
+	store.Logger = appLog.Out
+	if migrate {
+		legacyPath := downfile.GetCacheFilePath()
+		if migrated, err := store.MigrateLegacyCache(legacyPath); err != nil {
+			appLog.Warnf("main.migrate_failed", err)
+		} else if migrated > 0 {
+			appLog.Infof("main.migrated", migrated, legacyPath, store.FilePath())
+		}
+	}
+	return store
+}
+
+// taskFilter 获取命令行中设置的下载任务选择条件
+func (config *AppConfig) taskFilter() downfile.TaskFilter {
+	return downfile.TaskFilter{
+		Groups:   config.Groups,
+		Modules:  config.Modules,
+		Tags:     config.Tags,
+		Excludes: config.Excludes,
+	}
+}
+
+// selectTasks 将配置组展开为下载任务，只保留满足命令行选择条件的下载项
+// enableAll 为 false 时只保留 enable=true 的项
+func (config *AppConfig) selectTasks(groups downfile.DownConfig, enableAll bool) []downfile.DownTask {
+	filter := config.taskFilter()
+	tasks := downfile.FilterDownTasks(downfile.BuildDownTasks(groups, enableAll), filter)
+	if len(tasks) == 0 && !filter.IsEmpty() {
+		appLog.Warnf("main.filter_no_match", filter)
+	}
+	return tasks
+}
+
+// validateConfig 检查配置文件并输出发现的问题，有错误时返回配置错误退出码
+func validateConfig(appConfig *AppConfig, parser *flags.Parser) int {
+	// 使用配置文件中设置的输出目录检查文件路径
+	if configFile, err := downfile.LoadConfigFile(appConfig.ConfigFile); err == nil {
+		applySettings(appConfig, parser, configFile.Settings)
+	}
+
+	issues, err := downfile.ValidateConfigFile(appConfig.ConfigFile, appConfig.OutputDir)
+	if err != nil {
+		appLog.Errorf("main.load_config_failed", err)
+		return ExitConfigError
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Level == downfile.IssueError {
+			errorCount++
+			appLog.Errorf("main.validate_error", appConfig.ConfigFile, issue.Line, issue.Column, issue.Message)
+		} else {
+			appLog.Warnf("main.validate_warning", appConfig.ConfigFile, issue.Line, issue.Column, issue.Message)
+		}
+	}
+	if errorCount > 0 {
+		appLog.Errorf("main.validate_failed", errorCount, len(issues)-errorCount)
+		return ExitConfigError
+	}
+	appLog.Infof("main.validate_ok", appConfig.ConfigFile, len(issues))
+	return ExitOK
+}
+
+// applySettings 使用配置文件中的全局设置，命令行中明确指定的参数优先
+func applySettings(config *AppConfig, parser *flags.Parser, settings downfile.Settings) {
+	// explicit 判断参数是否在命令行中明确指定（而不是使用默认值）
+	explicit := func(longName string) bool {
+		option := parser.FindOptionByLongName(longName)
+		return option != nil && option.IsSet() && !option.IsSetDefault()
+	}
+
+	if settings.OutputDir != "" && !explicit("output") {
+		config.OutputDir = settings.OutputDir
+	}
+	if settings.Proxy != "" && !explicit("proxy") {
+		config.ProxyURL = settings.Proxy
+	}
+	if settings.Retries > 0 && !explicit("retries") {
+		config.Retries = settings.Retries
+	}
+	if settings.ConnectTimeout > 0 && !explicit("connect-timeout") {
+		config.ConnectTimeout = settings.ConnectTimeout
+	}
+	if settings.IdleTimeout > 0 && !explicit("idle-timeout") {
+		config.IdleTimeout = settings.IdleTimeout
+	}
+	if settings.TotalTimeout > 0 && !explicit("total-timeout") {
+		config.TotalTimeout = settings.TotalTimeout
+	}
+	if settings.CacheExpire > 0 && !explicit("cache-expire") {
+		config.CacheExpire = settings.CacheExpire
+	}
+	if settings.Concurrency > 0 && !explicit("concurrency") {
+		config.Concurrency = settings.Concurrency
+	}
+	if settings.KeepOld != nil && !explicit("keep-old") {
+		config.KeepOld = *settings.KeepOld
+	}
+	if settings.MirrorStrategy != "" && !explicit("mirror-strategy") {
+		config.MirrorStrategy = settings.MirrorStrategy
+	}
+	if settings.Segments > 0 && !explicit("segments") {
+		config.Segments = settings.Segments
+	}
+}
+
+// setupLogging 根据命令行参数设置消息语言及日志输出，返回关闭日志文件的函数
+// 使用文本格式输出到终端时保持原有的输出样式，否则使用 log/slog 输出结构化日志
+func setupLogging(config *AppConfig) (func(), error) {
+	lang := config.Lang
+	if lang == "" {
+		lang = downfile.DetectLanguage()
+	}
+	downfile.SetLanguage(lang)
+
+	level, err := downfile.ParseLogLevel(config.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	appLog = downfile.MsgLogger{Level: level}
+
+	// JSON格式的下载计划输出到标准输出，日志改为输出到标准错误
+	var out io.Writer = os.Stdout
+	if config.DryRun && config.PlanFormat == "json" {
+		out = os.Stderr
+	}
+	if config.LogFormat == "text" && config.LogFile == "" {
+		if out != os.Stdout {
+			appLog.Out = log.New(out, "", 0)
+		}
+		return func() {}, nil
+	}
+
+	closeLog := func() {}
+	if config.LogFile != "" {
+		if err := downfile.MakeDirs(config.LogFile, true); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(config.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		out = file
+		closeLog = func() { file.Close() }
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(out, options)
+	if config.LogFormat == "json" {
+		handler = slog.NewJSONHandler(out, options)
+	}
+	appLog.Out = downfile.NewSlogLogger(slog.New(handler))
+	return closeLog, nil
+}
+
+// argsLanguage 获取命令行参数中 --lang 指定的消息语言，未指定时根据环境变量判断
+func argsLanguage(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--lang="); ok && value != "" {
+			return value
+		}
+		if arg == "--lang" && i+1 < len(args) && args[i+1] != "" {
+			return args[i+1]
+		}
+	}
+	return downfile.DetectLanguage()
+}
+
+// localizeHelp 使用当前语言的消息设置命令行参数、子命令参数及位置参数的帮助信息
+// 全局参数的消息键为 help.<长参数名>，子命令参数为 help.<子命令>.<长参数名>
+func localizeHelp(parser *flags.Parser) {
+	localizeOptions(parser.Groups(), "help.")
+	for _, command := range parser.Commands() {
+		localizeOptions(append([]*flags.Group{command.Group}, command.Groups()...), "help."+command.Name+".")
+		for _, arg := range command.Args() {
+			arg.Description = downfile.Msg("help." + command.Name + "." + arg.Name)
+		}
+	}
+}
+
+// localizeOptions 设置参数组中所有有长参数名的参数的帮助信息，消息目录中没有对应消息时保持不变
+func localizeOptions(groups []*flags.Group, prefix string) {
+	for _, group := range groups {
+		for _, option := range group.Options() {
+			key := prefix + option.LongName
+			if option.LongName == "" || downfile.Msg(key) == key {
+				continue
+			}
+			option.Description = downfile.Msg(key)
+		}
+	}
 }
 
 func main() {
-	// 解析命令行参数
+	os.Exit(run(os.Args[1:]))
+}
+
+// run 解析命令行参数并执行子命令，返回进程退出码
+func run(arguments []string) int {
+	// 解析命令行参数前确定消息语言，帮助信息使用对应语言输出
+	downfile.SetLanguage(argsLanguage(arguments))
 	var appConfig AppConfig
+	var cleanCommand CleanCommand
+	var fetchCommand FetchCommand
 	parser := flags.NewParser(&appConfig, flags.Default)
 	parser.Name = "downtools"
 	parser.Usage = "[OPTIONS]"
+	addCommands(parser, &cleanCommand, &fetchCommand)
+	localizeHelp(parser)
 
 	// 解析命令行参数
-	_, err := parser.Parse()
+	args, err := parser.ParseArgs(arguments)
 	if err != nil {
 		var flagsErr *flags.Error
 		if errors.As(err, &flagsErr) && errors.Is(flagsErr.Type, flags.ErrHelp) {
-			os.Exit(0)
+			return ExitOK
 		}
+		return ExitUsageError
+	}
+
+	// 设置消息语言及日志输出
+	closeLog, err := setupLogging(&appConfig)
+	if err != nil {
+		appLog.Errorf("main.log_setup_failed", err)
+		return ExitUsageError
+	}
+	defer closeLog()
+
+	// 未被解析的参数（如拼写错误的子命令）不能当作 run 执行
+	if len(args) > 0 {
+		appLog.Errorf("main.unexpected_args", strings.Join(args, " "))
+		return ExitUsageError
 	}
 
 	// 显示版本信息后退出
 	if appConfig.Version {
-		fmt.Printf("自动下载工具 v%s\n", Version)
-		os.Exit(0)
+		appLog.Infof("main.title", Version)
+		return ExitOK
+	}
+
+	if err := appConfig.taskFilter().Validate(); err != nil {
+		appLog.Errorf("main.invalid_filter", err)
+		return ExitUsageError
+	}
+
+	switch activeCommand(parser) {
+	case CommandList:
+		return listItems(&appConfig, parser)
+	case CommandStatus:
+		return showStatus(&appConfig, parser)
+	case CommandValidate:
+		return validateConfig(&appConfig, parser)
+	case CommandClean:
+		return cleanState(&appConfig, parser, &cleanCommand)
+	case CommandFetch:
+		return fetchURL(&appConfig, parser, &fetchCommand)
+	}
+	return runDownloads(&appConfig, parser)
+}
+
+// runDownloads 下载配置文件中的下载项，返回进程退出码
+func runDownloads(appConfig *AppConfig, parser *flags.Parser) int {
+	configFile, ok := loadConfig(appConfig, parser)
+	if !ok {
+		return ExitConfigError
 	}
 
 	// 显示程序信息
 	appConfig.DisplayConfig()
 
-	// 读取配置文件
-	downloadConfig, err := downfile.LoadConfig(appConfig.ConfigFile)
-	if err != nil {
-		fmt.Printf("加载配置文件失败: %v\n", err)
-		return
+	// 打开状态存储并清理过期缓存记录，预演模式不修改下载缓存
+	cache := appConfig.openStateStore(!appConfig.DryRun)
+	if !appConfig.DryRun {
+		cache.CleanupExpired()
 	}
 
-	// 清理过期缓存记录
-	downfile.CacheExpireHours = appConfig.CacheExpire
-	downfile.CleanupExpiredCache()
-
 	// 创建HTTP客户端配置
 	clientConfig := &downfile.ClientConfig{
 		ConnectTimeout: appConfig.ConnectTimeout,
@@ -88,31 +408,261 @@ func main() {
 	// 创建HTTP客户端
 	httpClient, err := downfile.CreateHTTPClient(clientConfig)
 	if err != nil {
-		fmt.Printf("创建HTTP客户端失败: %v\n", err)
-		return
+		appLog.Errorf("main.create_client_failed", err)
+		return ExitConfigError
 	}
 
-	// 处理所有配置组
-	totalItems := 0
-	successItems := 0
-
-	for groupName, downItems := range downloadConfig {
-		// 如果未启用enable过滤，则只处理enable=true的项
-		if !appConfig.EnableAll {
-			downItems = downfile.FilterEnableItems(downItems)
+	// 处理满足选择条件的下载项 如果未启用enable过滤，则只处理enable=true的项
+	downTasks := appConfig.selectTasks(configFile.Groups, appConfig.EnableAll)
+	ctx := context.Background()
+	if appConfig.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, appConfig.TotalTimeout)
+		defer cancel()
+	}
+	startTime := time.Now()
+	progressOption := downfile.WithProgressMode(appConfig.Progress)
+	if appConfig.Progress == downfile.ProgressJSON {
+		// JSON事件输出到标准错误，与标准输出的日志分离
+		progressOption = downfile.WithProgressReporter(downfile.NewJSONProgressReporter(os.Stderr))
+	}
+	options := []downfile.Option{
+		progressOption,
+		downfile.WithLogLevel(appLog.Level),
+		downfile.WithHTTPClient(httpClient),
+		downfile.WithCache(cache),
+		downfile.WithOutputDir(appConfig.OutputDir),
+		downfile.WithForceUpdate(appConfig.ForceUpdate),
+		downfile.WithKeepOld(appConfig.KeepOld),
+		downfile.WithRetries(appConfig.Retries),
+		downfile.WithConcurrency(appConfig.Concurrency),
+		downfile.WithMirrorStrategy(appConfig.MirrorStrategy),
+		downfile.WithSegments(appConfig.Segments),
+		downfile.WithRewriteRules(configFile.RewriteRules),
+		downfile.WithGitHubAPI(appConfig.GitHubAPI),
+		downfile.WithSpeedPolicy(downfile.SpeedPolicy{
+			MinSpeed:      appConfig.MinSpeed,
+			CheckInterval: time.Duration(appConfig.SpeedInterval) * time.Second,
+			GracePeriod:   time.Duration(appConfig.SpeedGrace) * time.Second,
+		}),
+	}
+	if appLog.Out != nil {
+		options = append(options, downfile.WithLogger(appLog.Out))
+	}
+	if appConfig.Locked {
+		lock, err := downfile.LoadLockFile(appConfig.LockFile)
+		if err != nil {
+			appLog.Errorf("main.load_lock_failed", err)
+			return ExitConfigError
 		}
-		if len(downItems) > 0 {
-			fmt.Printf("\n处理配置组: %s\n", groupName)
-			success := downfile.ProcessDownItems(httpClient, downItems, appConfig.OutputDir, appConfig.ForceUpdate, appConfig.KeepOld, appConfig.Retries)
-			totalItems += len(downItems)
-			successItems += success
+		if len(lock.Items) == 0 {
+			appLog.Errorf("main.lock_empty", appConfig.LockFile)
+			return ExitConfigError
 		}
+		options = append(options, downfile.WithLock(lock))
+	}
+	downloader, err := downfile.NewDownloader(options...)
+	if err != nil {
+		appLog.Errorf("main.create_downloader_failed", err)
+		return ExitConfigError
 	}
 
+	// 预演模式只输出下载计划，不下载文件
+	if appConfig.DryRun {
+		return showPlan(downloader.Plan(ctx, downTasks, appConfig.Probe), appConfig.PlanFormat)
+	}
+
+	// 探测模式只检查下载源，不下载文件
+	if appConfig.Probe {
+		return probeExitCode(downloader.Probe(ctx, downTasks))
+	}
+
+	results := downloader.Download(ctx, downTasks)
+
 	// 清理未完成的下载文件
-	if err := downfile.CleanupIncompleteDownloads(appConfig.OutputDir); err != nil {
-		fmt.Printf("清理未完成下载文件失败: %v\n", err)
+	if err := downfile.CleanupIncompleteDownloads(appConfig.OutputDir, appLog); err != nil {
+		appLog.Warnf("main.cleanup_failed", err)
+	} else {
+		appLog.Infof("main.cleanup_done")
+	}
+
+	// 显示下载汇总
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		appLog.Warnf("main.total_timeout_exceeded", appConfig.TotalTimeout)
+	}
+	displayGroupSummary(results)
+	successItems := downfile.CountSuccess(results)
+	appLog.Infof("main.summary", successItems, len(results)-successItems, len(results))
+
+	// 所有下载项处理成功后更新锁定文件，锁定模式下不修改锁定文件
+	if appConfig.LockFile != "" && !appConfig.Locked && len(results) > 0 {
+		if successItems == len(results) {
+			updateLockFile(appConfig.LockFile, downloader, results, configFile.Groups)
+		} else {
+			appLog.Warnf("main.lock_not_updated", appConfig.LockFile)
+		}
+	}
+
+	// 生成运行报告
+	if appConfig.ReportFile != "" {
+		if err := downfile.WriteReport(appConfig.ReportFile, appConfig.ReportFormat, startTime, results); err != nil {
+			appLog.Errorf("main.report_failed", err)
+		} else {
+			appLog.Infof("main.report_saved", appConfig.ReportFile)
+		}
+	}
+
+	return exitCode(results, appConfig.FailOn, appConfig.OutputDir)
+}
+
+// updateLockFile 使用下载结果更新锁定文件，未处理的下载项保留原有记录，删除配置文件中已不存在的下载项
+func updateLockFile(path string, downloader *downfile.Downloader, results []downfile.DownResult, groups downfile.DownConfig) {
+	lock, err := downfile.LoadLockFile(path)
+	if err != nil {
+		appLog.Warnf("main.lock_failed", err)
+		return
+	}
+	entries, err := downloader.LockEntries(results)
+	if err != nil {
+		appLog.Warnf("main.lock_failed", err)
+		return
+	}
+	lock.Update(entries, downfile.BuildDownTasks(groups, true))
+	if err := lock.Save(path); err != nil {
+		appLog.Warnf("main.lock_failed", err)
+		return
+	}
+	appLog.Infof("main.lock_saved", path, len(lock.Items))
+}
+
+// showPlan 输出下载计划并计算退出码，有下载项解析或探测失败时计为失败
+func showPlan(plans []downfile.PlanItem, format string) int {
+	if format == "json" {
+		if err := downfile.WritePlanJSON(os.Stdout, plans); err != nil {
+			appLog.Errorf("main.plan_write_failed", err)
+			return ExitUsageError
+		}
 	} else {
-		fmt.Println("未完成下载文件清理完成")
+		displayPlan(plans)
+	}
+
+	failed := 0
+	for _, plan := range plans {
+		if !plan.OK() {
+			failed++
+		}
+	}
+	return failureExitCode(failed, len(plans))
+}
+
+// displayPlan 按配置组输出文本格式的下载计划
+func displayPlan(plans []downfile.PlanItem) {
+	group := ""
+	for _, plan := range plans {
+		if plan.Group != group {
+			group = plan.Group
+			appLog.Infof("main.plan_group", group)
+		}
+		reason := downfile.Msg("main.plan_reason_" + plan.Reason)
+		if plan.Action == downfile.PlanReplace && plan.KeepOld {
+			reason += downfile.Msg("main.plan_keep_old")
+		}
+		appLog.Infof("main.plan_item", downfile.Msg("main.plan_"+plan.Action), plan.Module, plan.Path, reason)
+		if plan.Tag != "" {
+			appLog.Infof("main.plan_release", plan.Release, plan.Tag)
+		}
+		if plan.Error != "" {
+			appLog.Errorf("main.plan_error", plan.Error)
+		}
+		if plan.Mirrors == nil {
+			for _, downloadURL := range plan.URLs {
+				appLog.Infof("main.plan_url", downloadURL)
+			}
+			continue
+		}
+		for _, mirror := range plan.Mirrors {
+			if mirror.Error != "" {
+				appLog.Warnf("main.plan_mirror_failed", mirror.URL, mirror.Error)
+				continue
+			}
+			size := downfile.Msg("probe.size_unknown")
+			if mirror.Size >= 0 {
+				size = downfile.FormatSize(mirror.Size)
+			}
+			appLog.Infof("main.plan_mirror", mirror.URL, mirror.Status, size)
+		}
+	}
+	download, replace, skip := downfile.PlanCounts(plans)
+	appLog.Infof("main.plan_summary", download, replace, skip, len(plans))
+}
+
+// probeExitCode 输出探测汇总并计算退出码
+func probeExitCode(results []downfile.ProbeResult) int {
+	ok := 0
+	for _, result := range results {
+		if result.OK() {
+			ok++
+		}
+	}
+	appLog.Infof("main.probe_summary", ok, len(results)-ok, len(results))
+	return failureExitCode(len(results)-ok, len(results))
+}
+
+// exitCode 根据下载结果及失败判定策略计算退出码
+func exitCode(results []downfile.DownResult, failOn, outputDir string) int {
+	failed := 0
+	for _, result := range results {
+		if isFailure(result, failOn, outputDir) {
+			failed++
+		}
+	}
+	return failureExitCode(failed, len(results))
+}
+
+// failureExitCode 根据失败数量及总数计算退出码，没有处理任何下载项时视为成功
+func failureExitCode(failed, total int) int {
+	switch {
+	case failed == 0:
+		return ExitOK
+	case failed == total:
+		return ExitAllFailed
+	default:
+		return ExitPartialFail
+	}
+}
+
+// isFailure 判断下载结果在失败判定策略下是否计为失败
+func isFailure(result downfile.DownResult, failOn, outputDir string) bool {
+	if result.Success() {
+		return false
+	}
+	switch failOn {
+	case FailOnNever:
+		return false
+	case FailOnMissing:
+		return !downfile.FileExists(downfile.GetItemFilePath(result.FileName, outputDir))
+	default:
+		return true
+	}
+}
+
+// displayGroupSummary 按配置组显示下载结果
+func displayGroupSummary(results []downfile.DownResult) {
+	var groups []string
+	groupResults := make(map[string][]downfile.DownResult)
+	for _, result := range results {
+		if _, exists := groupResults[result.Group]; !exists {
+			groups = append(groups, result.Group)
+		}
+		groupResults[result.Group] = append(groupResults[result.Group], result)
+	}
+	for _, group := range groups {
+		success := downfile.CountSuccess(groupResults[group])
+		appLog.Infof("main.group_summary", group, success, len(groupResults[group]))
+		for _, result := range groupResults[group] {
+			if !result.Success() {
+				appLog.Warnf("main.group_failed_item", result.Module, result.Status, result.Error)
+			}
+		}
 	}
 }
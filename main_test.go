@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/winezer0/downtools/downfile"
+)
+
+func TestRunRejectsUnexpectedArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "拼写错误的子命令", args: []string{"lsit"}},
+		{name: "多余的位置参数", args: []string{"-c", "missing.yaml", "extra"}},
+		{name: "fetch多余的参数", args: []string{"fetch", "https://example.com/a.bin", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := run(tt.args); code != ExitUsageError {
+				t.Errorf("run(%q) = %d, want %d", tt.args, code, ExitUsageError)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	outputDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outputDir, "old.bin"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ok := downfile.DownResult{FileName: "a.bin", Status: downfile.StatusDownloaded}
+	skipped := downfile.DownResult{FileName: "b.bin", Status: downfile.StatusSkipped}
+	failed := downfile.DownResult{FileName: "c.bin", Status: downfile.StatusFailed}
+	notFound := downfile.DownResult{FileName: "d.bin", Status: downfile.StatusNotFound}
+	failedWithOld := downfile.DownResult{FileName: "old.bin", Status: downfile.StatusFailed}
+
+	tests := []struct {
+		name    string
+		results []downfile.DownResult
+		failOn  string
+		want    int
+	}{
+		{name: "全部成功", results: []downfile.DownResult{ok, skipped}, failOn: FailOnError, want: ExitOK},
+		{name: "没有下载项", failOn: FailOnError, want: ExitOK},
+		{name: "部分失败", results: []downfile.DownResult{ok, failed}, failOn: FailOnError, want: ExitPartialFail},
+		{name: "全部失败", results: []downfile.DownResult{failed, notFound}, failOn: FailOnError, want: ExitAllFailed},
+		{name: "本地文件可用时不计为失败", results: []downfile.DownResult{ok, failedWithOld}, failOn: FailOnMissing, want: ExitOK},
+		{name: "本地文件缺失时计为失败", results: []downfile.DownResult{failedWithOld, failed}, failOn: FailOnMissing, want: ExitPartialFail},
+		{name: "从不失败", results: []downfile.DownResult{failed, notFound}, failOn: FailOnNever, want: ExitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.results, tt.failOn, outputDir); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	// writeConfig 写入包含指定下载地址的配置文件
+	writeConfig := func(t *testing.T, dir string, paths ...string) string {
+		t.Helper()
+		config := "files:\n"
+		for i, p := range paths {
+			config += fmt.Sprintf("  - module: m%d\n    filename: m%d.bin\n    enable: true\n    download-urls: [%s%s]\n", i, i, server.URL, p)
+		}
+		configPath := filepath.Join(dir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return configPath
+	}
+
+	tests := []struct {
+		name   string
+		config func(t *testing.T, dir string) string
+		extra  []string
+		want   int
+	}{
+		{name: "全部成功", config: func(t *testing.T, dir string) string { return writeConfig(t, dir, "/ok.bin") }, want: ExitOK},
+		{name: "部分失败", config: func(t *testing.T, dir string) string { return writeConfig(t, dir, "/ok.bin", "/missing.bin") }, want: ExitPartialFail},
+		{name: "全部失败", config: func(t *testing.T, dir string) string { return writeConfig(t, dir, "/a.bin", "/b.bin") }, want: ExitAllFailed},
+		{name: "配置文件不存在", config: func(t *testing.T, dir string) string { return filepath.Join(dir, "missing.yaml") }, want: ExitConfigError},
+		{name: "配置文件格式错误", config: func(t *testing.T, dir string) string {
+			configPath := filepath.Join(dir, "config.yaml")
+			os.WriteFile(configPath, []byte("files: [unclosed\n"), 0644)
+			return configPath
+		}, want: ExitConfigError},
+		{name: "无效的参数", config: func(t *testing.T, dir string) string { return writeConfig(t, dir, "/ok.bin") }, extra: []string{"--retries", "abc"}, want: ExitUsageError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			args := []string{
+				"-c", tt.config(t, dir),
+				"-o", filepath.Join(dir, "out"),
+				"--lock-file", filepath.Join(dir, "downtools.lock"),
+				"--progress", "none",
+				"--log-level", "error",
+			}
+			if code := run(append(args, tt.extra...)); code != tt.want {
+				t.Errorf("run() = %d, want %d", code, tt.want)
+			}
+			if tt.want == ExitOK && !downfile.FileExists(filepath.Join(dir, "out", "m0.bin")) {
+				t.Error("file not downloaded")
+			}
+		})
+	}
+}
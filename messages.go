@@ -0,0 +1,311 @@
+package main
+
+import "github.com/winezer0/downtools/downfile"
+
+func init() {
+	downfile.RegisterMessages(downfile.LangZH, map[string]string{
+		"main.title":                         "自动下载工具 %s",
+		"main.config_file":                   "配置文件: %s",
+		"main.output_dir":                    "输出目录: %s",
+		"main.connect_timeout":               "连接超时: %d秒",
+		"main.idle_timeout":                  "空闲超时: %d秒",
+		"main.retries":                       "重试次数: %d次",
+		"main.keep_old":                      "保留旧文件: %v",
+		"main.proxy":                         "使用代理: %s",
+		"main.force":                         "启用强制更新: %v",
+		"main.cache_expire":                  "缓存过期时间: %v小时",
+		"main.state_dir":                     "状态目录: %s",
+		"main.migrate_failed":                "迁移旧版缓存记录失败: %v",
+		"main.migrated":                      "已将 %d 条缓存记录从 %s 迁移到 %s",
+		"main.enable_all":                    "下载未启用项: %v",
+		"main.filter":                        "选择条件: %s",
+		"main.filter_no_match":               "没有满足选择条件的下载项: %s",
+		"main.invalid_filter":                "选择条件错误: %v",
+		"main.create_downloader_failed":      "创建下载器失败: %v",
+		"main.unexpected_args":               "未知的子命令或参数: %s",
+		"main.list_tags":                     "      标签: %s",
+		"main.concurrency":                   "并发下载数量: %d",
+		"main.progress":                      "进度输出模式: %s",
+		"main.total_timeout":                 "总超时时间: %v",
+		"main.report":                        "运行报告: %s (%s)",
+		"main.min_speed":                     "最小下载速度: %v字节/秒 (检测间隔%d秒, 宽限%d秒)",
+		"main.blank":                         "",
+		"main.log_setup_failed":              "设置日志输出失败: %v",
+		"main.load_config_failed":            "加载配置文件失败: %v",
+		"main.create_client_failed":          "创建HTTP客户端失败: %v",
+		"main.cleanup_failed":                "清理未完成下载文件失败: %v",
+		"main.cleanup_done":                  "未完成下载文件清理完成",
+		"main.total_timeout_exceeded":        "已超过总运行时间限制 (%v)，部分下载项未完成",
+		"main.summary":                       "下载汇总: 成功 %d 项, 失败 %d 项, 共 %d 项",
+		"main.report_failed":                 "生成运行报告失败: %v",
+		"main.report_saved":                  "运行报告已保存到: %s",
+		"main.group_summary":                 "配置组 %s: 成功 %d 项, 共 %d 项",
+		"main.group_failed_item":             "  失败: %s (%s) %s",
+		"main.mirror_strategy":               "下载源排序策略: %s",
+		"main.segments":                      "分段下载连接数: %d",
+		"main.validate_error":                "%s:%d:%d: 错误: %s",
+		"main.validate_warning":              "%s:%d:%d: 警告: %s",
+		"main.validate_failed":               "配置检查未通过: %d 个错误, %d 个警告",
+		"main.validate_ok":                   "配置文件 %s 检查通过 (%d 个警告)",
+		"main.probe_summary":                 "探测汇总: 正常 %d 项, 异常 %d 项, 共 %d 项",
+		"main.locked":                        "锁定模式: 只下载 %s 中记录的文件",
+		"main.load_lock_failed":              "加载锁定文件失败: %v",
+		"main.lock_empty":                    "锁定文件 %s 不存在或没有任何记录，请先在非锁定模式下成功运行一次",
+		"main.lock_not_updated":              "部分下载项处理失败，未更新锁定文件 %s",
+		"main.lock_failed":                   "更新锁定文件失败: %v",
+		"main.lock_saved":                    "锁定文件已更新: %s (%d 项)",
+		"main.plan_write_failed":             "输出下载计划失败: %v",
+		"main.plan_group":                    "配置组 %s:",
+		"main.plan_item":                     "  [%s] %s -> %s (%s)",
+		"main.plan_download":                 "下载",
+		"main.plan_replace":                  "替换",
+		"main.plan_skip":                     "跳过",
+		"main.plan_reason_missing":           "本地文件不存在",
+		"main.plan_reason_force":             "强制更新",
+		"main.plan_reason_keep-updated":      "缓存记录过期或可以检查服务器文件是否变化",
+		"main.plan_reason_cached":            "缓存记录未过期",
+		"main.plan_reason_exists":            "本地文件已存在且未设置 keep-updated",
+		"main.plan_reason_release-unchanged": "Release 标签及文件大小未变化",
+		"main.plan_reason_locked":            "本地文件与锁定记录一致",
+		"main.plan_reason_lock-mismatch":     "本地文件与锁定记录不一致",
+		"main.plan_keep_old":                 "，保留旧文件",
+		"main.plan_release":                  "      GitHub Release: %s %s",
+		"main.plan_error":                    "      错误: %s",
+		"main.plan_url":                      "      %s",
+		"main.plan_mirror":                   "      %s (HTTP %d, %s)",
+		"main.plan_mirror_failed":            "      %s 不可用: %s",
+		"main.plan_summary":                  "下载计划: 下载 %d 项, 替换 %d 项, 跳过 %d 项, 共 %d 项",
+		"main.enabled":                       "启用",
+		"main.disabled":                      "禁用",
+		"main.list_group":                    "配置组 %s: 启用 %d 项, 共 %d 项",
+		"main.list_item":                     "  [%s] %s -> %s (%d 个下载地址)",
+		"main.list_release":                  "      GitHub Release: %s (%s)",
+		"main.status_group":                  "配置组 %s:",
+		"main.status_item":                   "  %s -> %s",
+		"main.status_file":                   "    本地文件: %s, 修改于 %s",
+		"main.status_file_missing":           "    本地文件: 不存在",
+		"main.status_cache":                  "    缓存记录: %s前下载",
+		"main.status_cache_tag":              "    缓存记录: %s前下载 (标签 %s)",
+		"main.status_cache_missing":          "    缓存记录: 无",
+		"main.status_partial":                "    未完成下载: %s",
+		"main.status_update":                 "    下次运行: 下载或检查更新",
+		"main.status_skip":                   "    下次运行: 跳过",
+		"main.status_summary":                "状态汇总: 下次运行将处理 %d 项, 共 %d 项",
+		"main.clean_cache_failed":            "清理下载缓存失败: %v",
+		"main.clean_cache_done":              "已清理下载缓存: %s",
+		"main.clean_partial_failed":          "清理未完成下载文件失败: %v",
+		"main.clean_partial_done":            "已删除 %d 个未完成下载文件 (%s)",
+		"main.fetch_no_filename":             "无法从下载地址获取文件名，请使用 -o 指定保存路径: %s",
+		"main.fetch_start":                   "下载 %s 到 %s",
+		"main.fetch_failed":                  "下载失败: %v",
+		"main.fetch_done":                    "下载完成: %s",
+	})
+	downfile.RegisterMessages(downfile.LangEN, map[string]string{
+		"main.title":                         "Auto download tool %s",
+		"main.config_file":                   "Config file: %s",
+		"main.output_dir":                    "Output directory: %s",
+		"main.connect_timeout":               "Connect timeout: %ds",
+		"main.idle_timeout":                  "Idle timeout: %ds",
+		"main.retries":                       "Retries: %d",
+		"main.keep_old":                      "Keep old files: %v",
+		"main.proxy":                         "Proxy: %s",
+		"main.force":                         "Force update: %v",
+		"main.cache_expire":                  "Cache expiry: %v hours",
+		"main.state_dir":                     "State directory: %s",
+		"main.migrate_failed":                "Failed to migrate legacy cache entries: %v",
+		"main.migrated":                      "Migrated %d cache entries from %s to %s",
+		"main.enable_all":                    "Download disabled items: %v",
+		"main.filter":                        "Item filter: %s",
+		"main.filter_no_match":               "No items match the filter: %s",
+		"main.invalid_filter":                "Invalid item filter: %v",
+		"main.create_downloader_failed":      "Failed to create downloader: %v",
+		"main.unexpected_args":               "Unknown command or argument: %s",
+		"main.list_tags":                     "      Tags: %s",
+		"main.concurrency":                   "Concurrency: %d",
+		"main.progress":                      "Progress mode: %s",
+		"main.total_timeout":                 "Total timeout: %v",
+		"main.report":                        "Run report: %s (%s)",
+		"main.min_speed":                     "Minimum speed: %v bytes/s (check interval %ds, grace %ds)",
+		"main.blank":                         "",
+		"main.log_setup_failed":              "Failed to set up logging: %v",
+		"main.load_config_failed":            "Failed to load config file: %v",
+		"main.create_client_failed":          "Failed to create HTTP client: %v",
+		"main.cleanup_failed":                "Failed to clean up partial downloads: %v",
+		"main.cleanup_done":                  "Partial downloads cleaned up",
+		"main.total_timeout_exceeded":        "Total run time limit (%v) exceeded, some items were not completed",
+		"main.summary":                       "Summary: %d succeeded, %d failed, %d total",
+		"main.report_failed":                 "Failed to write run report: %v",
+		"main.report_saved":                  "Run report saved to: %s",
+		"main.group_summary":                 "Group %s: %d of %d succeeded",
+		"main.group_failed_item":             "  Failed: %s (%s) %s",
+		"main.mirror_strategy":               "Mirror strategy: %s",
+		"main.segments":                      "Segments per download: %d",
+		"main.validate_error":                "%s:%d:%d: error: %s",
+		"main.validate_warning":              "%s:%d:%d: warning: %s",
+		"main.validate_failed":               "Config validation failed: %d errors, %d warnings",
+		"main.validate_ok":                   "Config file %s is valid (%d warnings)",
+		"main.probe_summary":                 "Probe summary: %d ok, %d with problems, %d total",
+		"main.locked":                        "Locked mode: only downloading files recorded in %s",
+		"main.load_lock_failed":              "Failed to load lock file: %v",
+		"main.lock_empty":                    "Lock file %s is missing or empty, run once without --locked first",
+		"main.lock_not_updated":              "Some items failed, lock file %s was not updated",
+		"main.lock_failed":                   "Failed to update lock file: %v",
+		"main.lock_saved":                    "Lock file updated: %s (%d items)",
+		"main.plan_write_failed":             "Failed to write download plan: %v",
+		"main.plan_group":                    "Group %s:",
+		"main.plan_item":                     "  [%s] %s -> %s (%s)",
+		"main.plan_download":                 "download",
+		"main.plan_replace":                  "replace",
+		"main.plan_skip":                     "skip",
+		"main.plan_reason_missing":           "local file missing",
+		"main.plan_reason_force":             "forced update",
+		"main.plan_reason_keep-updated":      "cache entry expired or server file can be checked for changes",
+		"main.plan_reason_cached":            "cache entry not expired",
+		"main.plan_reason_exists":            "local file exists and keep-updated is not set",
+		"main.plan_reason_release-unchanged": "release tag and file size unchanged",
+		"main.plan_reason_locked":            "local file matches the lock file",
+		"main.plan_reason_lock-mismatch":     "local file differs from the lock file",
+		"main.plan_keep_old":                 ", keeping old file",
+		"main.plan_release":                  "      GitHub Release: %s %s",
+		"main.plan_error":                    "      error: %s",
+		"main.plan_url":                      "      %s",
+		"main.plan_mirror":                   "      %s (HTTP %d, %s)",
+		"main.plan_mirror_failed":            "      %s unavailable: %s",
+		"main.plan_summary":                  "Download plan: %d to download, %d to replace, %d to skip, %d total",
+		"main.enabled":                       "enabled",
+		"main.disabled":                      "disabled",
+		"main.list_group":                    "Group %s: %d enabled, %d total",
+		"main.list_item":                     "  [%s] %s -> %s (%d URLs)",
+		"main.list_release":                  "      GitHub Release: %s (%s)",
+		"main.status_group":                  "Group %s:",
+		"main.status_item":                   "  %s -> %s",
+		"main.status_file":                   "    Local file: %s, modified %s",
+		"main.status_file_missing":           "    Local file: missing",
+		"main.status_cache":                  "    Cache entry: downloaded %s ago",
+		"main.status_cache_tag":              "    Cache entry: downloaded %s ago (tag %s)",
+		"main.status_cache_missing":          "    Cache entry: none",
+		"main.status_partial":                "    Partial download: %s",
+		"main.status_update":                 "    Next run: download or check for updates",
+		"main.status_skip":                   "    Next run: skip",
+		"main.status_summary":                "Status summary: %d of %d items will be processed on the next run",
+		"main.clean_cache_failed":            "Failed to clear download cache: %v",
+		"main.clean_cache_done":              "Download cache cleared: %s",
+		"main.clean_partial_failed":          "Failed to remove partial downloads: %v",
+		"main.clean_partial_done":            "Removed %d partial downloads (%s)",
+		"main.fetch_no_filename":             "Cannot derive a file name from the URL, use -o to set the output path: %s",
+		"main.fetch_start":                   "Downloading %s to %s",
+		"main.fetch_failed":                  "Download failed: %v",
+		"main.fetch_done":                    "Download completed: %s",
+	})
+	// 命令行参数及子命令的帮助信息，键为 help.<长参数名>、help.<子命令>.<长参数名> 及 help.command.<子命令>
+	downfile.RegisterMessages(downfile.LangZH, map[string]string{
+		"help.config":                "配置文件路径",
+		"help.output":                "下载文件保存目录",
+		"help.connect-timeout":       "连接超时时间（秒）",
+		"help.idle-timeout":          "空闲超时时间（秒）",
+		"help.retries":               "下载失败重试次数",
+		"help.keep-old":              "保留旧文件（重命名为.old）",
+		"help.force":                 "强制更新，忽略缓存",
+		"help.proxy":                 "代理URL（支持http://和socks5://格式）",
+		"help.state-dir":             "状态目录（下载缓存及下载源统计），为空时使用输出目录下的 .downtools",
+		"help.cache-expire":          "缓存过期时间（小时）",
+		"help.enable-all":            "下载所有项 即使enable=false",
+		"help.group":                 "只处理名称匹配的配置组（可重复指定，支持通配符）",
+		"help.module":                "只处理模块名匹配的下载项（可重复指定，支持通配符）",
+		"help.tag":                   "只处理包含匹配标签的下载项（可重复指定，支持通配符）",
+		"help.exclude":               "排除模块名或配置组名称匹配的下载项（可重复指定，支持通配符）",
+		"help.concurrency":           "并发下载数量（跨配置组）",
+		"help.min-speed":             "最小要求下载速度（字节/秒），低于此值中止下载，0表示不检测",
+		"help.speed-interval":        "下载速度检测间隔（秒）",
+		"help.speed-grace":           "开始下载后不检测速度的宽限时间（秒）",
+		"help.total-timeout":         "整个运行的最长时间（如 30m），0表示不限制",
+		"help.lock-file":             "锁定文件路径，所有下载项处理成功后记录下载地址、大小及SHA256，为空时不生成",
+		"help.locked":                "只下载锁定文件中记录的文件，SHA256与锁定记录不一致时失败",
+		"help.report":                "运行报告输出文件，为空时不生成报告",
+		"help.report-format":         "运行报告格式",
+		"help.fail-on":               "计为失败的下载结果: error 下载失败或资源不存在, missing 仅本地没有可用文件时, never 不因下载结果失败",
+		"help.progress":              "进度输出模式: auto 输出为终端时显示进度条否则按行输出, tty 进度条, plain 按行输出, json 向标准错误输出JSON Lines事件, none 不输出",
+		"help.mirror-strategy":       "默认下载源排序策略: ordered 按配置顺序, fastest 历史速度最快优先, healthiest 历史成功率最高优先, random 随机",
+		"help.segments":              "分段下载的并行连接数（服务器支持范围请求时），1表示不分段",
+		"help.github-api":            "GitHub API 地址，用于解析 github-release 下载项",
+		"help.probe":                 "只探测所有下载源的可用性及文件大小是否一致，不下载文件",
+		"help.dry-run":               "只输出下载计划（下载、替换或跳过），不写入任何文件，也不修改下载缓存；与 --probe 同时使用时探测下载源的状态及文件大小",
+		"help.plan-format":           "下载计划输出格式: text 文本, json 向标准输出输出JSON（日志输出到标准错误）",
+		"help.log-level":             "日志级别",
+		"help.log-format":            "日志格式: text 文本, json 每行一条JSON日志",
+		"help.log-file":              "日志输出文件，为空时输出到标准输出",
+		"help.lang":                  "消息语言（zh 或 en），为空时根据 LANG 环境变量判断",
+		"help.version":               "显示版本信息",
+		"help.clean.cache":           "只清理下载缓存（包括下载源统计）",
+		"help.clean.partial":         "只清理输出目录下的未完成下载文件（包括可以续传的）",
+		"help.fetch.output":          "文件保存路径，为空时使用下载地址中的文件名保存到输出目录",
+		"help.fetch.url":             "下载地址",
+		"help.command.run":           "下载配置文件中的下载项（默认）",
+		"help.command.run.long":      "下载配置文件中的下载项，未指定子命令时执行",
+		"help.command.list":          "列出配置组及下载项",
+		"help.command.list.long":     "列出配置文件中的配置组、下载项及其启用状态，不访问网络",
+		"help.command.status":        "显示下载项的本地状态",
+		"help.command.status.long":   "显示下载项的本地文件、缓存记录及下次运行是否会下载，不访问网络",
+		"help.command.validate":      "检查配置文件",
+		"help.command.validate.long": "严格检查配置文件：拒绝未知字段，检查下载地址、文件路径及重复的下载项，输出问题所在的行号和列号",
+		"help.command.clean":         "清理下载缓存及未完成下载文件",
+		"help.command.clean.long":    "删除下载缓存文件及输出目录下的未完成下载文件",
+		"help.command.fetch":         "下载单个文件",
+		"help.command.fetch.long":    "不使用配置文件，直接下载指定的下载地址",
+	})
+	downfile.RegisterMessages(downfile.LangEN, map[string]string{
+		"help.config":                "Config file path",
+		"help.output":                "Directory to save downloaded files",
+		"help.connect-timeout":       "Connect timeout (seconds)",
+		"help.idle-timeout":          "Idle timeout (seconds)",
+		"help.retries":               "Number of retries when a download fails",
+		"help.keep-old":              "Keep old files (renamed to .old)",
+		"help.force":                 "Force update, ignore the cache",
+		"help.proxy":                 "Proxy URL (http:// and socks5:// supported)",
+		"help.state-dir":             "State directory (download cache and source statistics), defaults to .downtools under the output directory",
+		"help.cache-expire":          "Cache expiration time (hours)",
+		"help.enable-all":            "Download all items, even those with enable=false",
+		"help.group":                 "Only process groups whose name matches (repeatable, wildcards supported)",
+		"help.module":                "Only process items whose module name matches (repeatable, wildcards supported)",
+		"help.tag":                   "Only process items with a matching tag (repeatable, wildcards supported)",
+		"help.exclude":               "Exclude items whose module or group name matches (repeatable, wildcards supported)",
+		"help.concurrency":           "Number of concurrent downloads (across groups)",
+		"help.min-speed":             "Minimum required download speed (bytes/second), slower downloads are aborted, 0 disables the check",
+		"help.speed-interval":        "Download speed check interval (seconds)",
+		"help.speed-grace":           "Grace period after a download starts before checking speed (seconds)",
+		"help.total-timeout":         "Maximum duration of the whole run (e.g. 30m), 0 means no limit",
+		"help.lock-file":             "Lock file path; records URL, size and SHA256 after all items succeed, empty disables it",
+		"help.locked":                "Only download files recorded in the lock file, fail when SHA256 does not match",
+		"help.report":                "Run report output file, empty disables the report",
+		"help.report-format":         "Run report format",
+		"help.fail-on":               "Results counted as failures: error failed or missing downloads, missing only when no local file is available, never do not fail on download results",
+		"help.progress":              "Progress output mode: auto progress bar on a terminal otherwise line output, tty progress bar, plain line output, json JSON Lines events to stderr, none no output",
+		"help.mirror-strategy":       "Default mirror ordering: ordered config order, fastest fastest history first, healthiest highest success rate first, random random order",
+		"help.segments":              "Parallel connections for segmented downloads (when the server supports range requests), 1 disables segmenting",
+		"help.github-api":            "GitHub API URL used to resolve github-release items",
+		"help.probe":                 "Only probe the availability and size consistency of all sources, do not download",
+		"help.dry-run":               "Only print the download plan (download, replace or skip), write no files and leave the cache untouched; with --probe also probes source status and size",
+		"help.plan-format":           "Download plan format: text text, json JSON to stdout (logs go to stderr)",
+		"help.log-level":             "Log level",
+		"help.log-format":            "Log format: text text, json one JSON log per line",
+		"help.log-file":              "Log output file, empty writes to stdout",
+		"help.lang":                  "Message language (zh or en), detected from the LANG environment variable when empty",
+		"help.version":               "Show version information",
+		"help.clean.cache":           "Only clean the download cache (including source statistics)",
+		"help.clean.partial":         "Only clean partial downloads in the output directory (including resumable ones)",
+		"help.fetch.output":          "Path to save the file, empty saves to the output directory using the file name from the URL",
+		"help.fetch.url":             "Download URL",
+		"help.command.run":           "Download items from the config file (default)",
+		"help.command.run.long":      "Download items from the config file, runs when no command is given",
+		"help.command.list":          "List groups and items",
+		"help.command.list.long":     "List groups, items and whether they are enabled, without network access",
+		"help.command.status":        "Show local status of items",
+		"help.command.status.long":   "Show the local file, cache record and whether the next run would download each item, without network access",
+		"help.command.validate":      "Check the config file",
+		"help.command.validate.long": "Strictly check the config file: reject unknown fields, check URLs, file paths and duplicate items, reporting line and column of each problem",
+		"help.command.clean":         "Clean the download cache and partial downloads",
+		"help.command.clean.long":    "Delete the download cache file and partial downloads in the output directory",
+		"help.command.fetch":         "Download a single file",
+		"help.command.fetch.long":    "Download the given URL directly without a config file",
+	})
+}